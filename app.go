@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,6 +15,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,38 +24,86 @@ type App struct {
 	ctx     context.Context
 	logger  *log.Logger
 	logFile *os.File
+
+	// configMu guards the loaded config snapshot and writes to config.json
+	configMu     sync.Mutex
+	loadedConfig *configSnapshot
+	watcher      *configWatcher
+
+	// vaultMu guards the unlocked secret vault, nil while locked
+	vaultMu sync.Mutex
+	vault   *unlockedVault
+
+	// routerCheck caches the syntax check of the custom router script
+	routerCheck syntaxCheckCache
+
+	// cpuSample keeps the CPU time of the CCR process between status checks
+	cpuSample cpuSample
+
+	// supervisor runs CCR as a child process in ServiceModeSupervised
+	supervisor supervisor
+
+	// watchdog restarts CCR after unexpected exits
+	watchdog watchdog
+
+	// applyMu keeps ApplyConfig calls from overlapping
+	applyMu sync.Mutex
 }
 
 // Config represents the Claude Code Router configuration
 type Config struct {
-	APIKEY            interface{} `json:"APIKEY,omitempty"`
-	PROXY_URL         interface{} `json:"PROXY_URL,omitempty"`
-	HOST              interface{} `json:"HOST,omitempty"`
-	PORT              interface{} `json:"PORT,omitempty"`
-	API_TIMEOUT_MS    interface{} `json:"API_TIMEOUT_MS,omitempty"`
-	LOG               interface{} `json:"LOG,omitempty"`
-	NPM_GLOBAL_PREFIX interface{} `json:"NPM_GLOBAL_PREFIX,omitempty"`
-	Providers         interface{} `json:"Providers,omitempty"`
-	Router            interface{} `json:"Router,omitempty"`
+	APIKEY             string     `json:"APIKEY,omitempty"`
+	PROXY_URL          string     `json:"PROXY_URL,omitempty"`
+	HOST               string     `json:"HOST,omitempty"`
+	PORT               int        `json:"PORT,omitempty"`
+	API_TIMEOUT_MS     int        `json:"API_TIMEOUT_MS,omitempty"`
+	LOG                bool       `json:"LOG"`
+	NPM_GLOBAL_PREFIX  string     `json:"NPM_GLOBAL_PREFIX,omitempty"`
+	CUSTOM_ROUTER_PATH string     `json:"CUSTOM_ROUTER_PATH,omitempty"`
+	Providers          []Provider `json:"Providers,omitempty"`
+	Router             Router     `json:"Router"`
+	// Fallback lists, per Router slot, the routes CCR tries in order when the
+	// slot's provider fails
+	Fallback map[string][]string `json:"fallback,omitempty"`
+	// Transformers registers custom transformer plugins
+	Transformers []TransformerPlugin `json:"transformers,omitempty"`
+
+	// fields keeps keys this manager does not model (StatusLine, ...) and
+	// the original key order of the file
+	fields objectFields
+	// issues records values that could not be decoded into the schema
+	issues []ValidationIssue
 }
 
 // Provider represents a model provider configuration
 type Provider struct {
-	Name        interface{} `json:"name"`
-	APIBaseURL  interface{} `json:"api_base_url"`
-	APIKey      interface{} `json:"api_key"`
-	Models      interface{} `json:"models"`
-	Transformer interface{} `json:"transformer,omitempty"`
+	Name        string               `json:"name"`
+	APIBaseURL  string               `json:"api_base_url"`
+	APIKey      string               `json:"api_key"`
+	Models      []string             `json:"models"`
+	Transformer *ProviderTransformer `json:"transformer,omitempty"`
+	// SavedName is the name the provider had when LoadConfig returned it. The
+	// UI sends it back so masked secrets follow a renamed provider; it is
+	// cleared before the config is written.
+	SavedName string `json:"savedName,omitempty"`
+
+	fields objectFields
 }
 
 // Router represents the routing configuration
 type Router struct {
-	Default              interface{} `json:"default,omitempty"`
-	Background           interface{} `json:"background,omitempty"`
-	Think                interface{} `json:"think,omitempty"`
-	LongContext          interface{} `json:"longContext,omitempty"`
-	LongContextThreshold interface{} `json:"longContextThreshold,omitempty"`
-	WebSearch            interface{} `json:"webSearch,omitempty"`
+	Default              string `json:"default,omitempty"`
+	Background           string `json:"background,omitempty"`
+	Think                string `json:"think,omitempty"`
+	LongContext          string `json:"longContext,omitempty"`
+	LongContextThreshold int    `json:"longContextThreshold,omitempty"`
+	WebSearch            string `json:"webSearch,omitempty"`
+	Image                string `json:"image,omitempty"`
+	// Slots holds any other named slot, such as ones added by newer CCR
+	// versions. They are written as plain Router keys.
+	Slots map[string]string `json:"-"`
+
+	fields objectFields
 }
 
 // NewApp creates a new App application struct
@@ -67,6 +117,9 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.recoverMaterializedConfig()
+	a.startConfigWatcher()
+	a.startWatchdog()
 }
 
 // shutdown is called when the app is closing
@@ -74,6 +127,12 @@ func (a *App) shutdown(ctx context.Context) {
 	if a.logger != nil {
 		a.logger.Printf("Application shutting down")
 	}
+	a.stopConfigWatcher()
+	a.stopWatchdog()
+	// 受管的 CCR 子进程随管理器一起退出
+	if err := a.stopSupervised(); err != nil && a.logger != nil {
+		a.logger.Printf("ERROR: %v", err)
+	}
 	if a.logFile != nil {
 		a.logFile.Close()
 	}
@@ -107,11 +166,11 @@ func (a *App) initLogger() {
 
 	// Create a new logger with timestamp prefix
 	a.logFile = logFile
-	a.logger = log.New(logFile, "", log.LstdFlags|log.Lshortfile)
+	a.logger = log.New(redactingWriter{w: logFile}, "", log.LstdFlags|log.Lshortfile)
 
 	// Also log to stderr for development
 	multiWriter := io.MultiWriter(logFile, os.Stderr)
-	log.SetOutput(multiWriter)
+	log.SetOutput(redactingWriter{w: multiWriter})
 
 	// Log that the application has started
 	a.logger.Printf("Application started")
@@ -177,8 +236,31 @@ func (a *App) GetConfigPath() string {
 	return filepath.Join(homeDir, ".claude-code-router", "config.json")
 }
 
-// LoadConfig loads the Claude Code Router configuration
+// LoadConfig loads the Claude Code Router configuration. Literal API keys are
+// masked; use RevealConfigSecret to show one.
 func (a *App) LoadConfig() (Config, error) {
+	// 持有锁读取，避免读到启动服务时临时写入的明文配置
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	config, data, err := a.readConfig()
+	if err != nil {
+		return config, err
+	}
+
+	// 记录加载时的文件内容，保存时据此检测外部修改
+	a.loadedConfig = newConfigSnapshot(data)
+
+	config = maskConfigSecrets(config)
+	for i := range config.Providers {
+		config.Providers[i].SavedName = config.Providers[i].Name
+	}
+	return config, nil
+}
+
+// readConfig reads and decodes config.json, also returning its raw content
+// (nil when the file does not exist)
+func (a *App) readConfig() (Config, []byte, error) {
 	var config Config
 
 	configPath := a.GetConfigPath()
@@ -187,7 +269,7 @@ func (a *App) LoadConfig() (Config, error) {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: %v", err)
 		}
-		return config, err
+		return config, nil, err
 	}
 
 	// Check if config file exists
@@ -196,7 +278,7 @@ func (a *App) LoadConfig() (Config, error) {
 		if a.logger != nil {
 			a.logger.Printf("Config file does not exist at %s, returning empty config", configPath)
 		}
-		return config, nil
+		return config, nil, nil
 	}
 
 	// Read config file
@@ -205,7 +287,7 @@ func (a *App) LoadConfig() (Config, error) {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: Failed to read config file at %s: %v", configPath, err)
 		}
-		return config, err
+		return config, nil, err
 	}
 
 	// Parse JSON
@@ -214,232 +296,96 @@ func (a *App) LoadConfig() (Config, error) {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: Failed to parse JSON config at %s: %v", configPath, err)
 		}
-		return config, err
-	}
-
-	// Handle all fields for compatibility
-	config = a.processConfigFields(config)
-
-	if a.logger != nil {
-		a.logger.Printf("Successfully loaded config from %s", configPath)
-	}
-
-	return config, nil
-}
-
-// processConfigFields handles type conversion for all config fields
-func (a *App) processConfigFields(config Config) Config {
-	// Handle LOG field compatibility (string or bool)
-	if config.LOG != nil {
-		config.LOG = a.convertToBool(config.LOG)
+		return config, nil, err
 	}
 
-	// Handle APIKEY field
-	if config.APIKEY != nil {
-		config.APIKEY = a.convertToString(config.APIKEY)
-	}
-
-	// Handle PROXY_URL field
-	if config.PROXY_URL != nil {
-		config.PROXY_URL = a.convertToString(config.PROXY_URL)
-	}
-
-	// Handle HOST field
-	if config.HOST != nil {
-		config.HOST = a.convertToString(config.HOST)
+	// 记录无法按模式解析的字段，而不是静默替换为默认值
+	for _, issue := range config.issues {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: %s: %s", issue.Path, issue.Message)
+		}
 	}
 
-	// Handle PORT field
-	if config.PORT != nil {
-		config.PORT = a.convertToInt(config.PORT, 3456)
-	} else {
-		config.PORT = 3456 // default value
-	}
+	config = a.applyConfigDefaults(config)
 
-	// Handle API_TIMEOUT_MS field compatibility
-	if config.API_TIMEOUT_MS != nil {
-		config.API_TIMEOUT_MS = a.convertToInt(config.API_TIMEOUT_MS, 600000)
-	} else {
-		config.API_TIMEOUT_MS = 600000 // default value
-	}
-
-	// Handle NPM_GLOBAL_PREFIX field
-	if config.NPM_GLOBAL_PREFIX != nil {
-		config.NPM_GLOBAL_PREFIX = a.convertToString(config.NPM_GLOBAL_PREFIX)
+	// 报告未设置的环境变量引用，不输出变量的值
+	for _, reference := range findEnvReferences(config) {
+		if !reference.Resolved && a.logger != nil {
+			a.logger.Printf("WARNING: %s references environment variable %s, which is not set", reference.Path, reference.Variable)
+		}
 	}
 
-	// Handle Providers field
-	if config.Providers != nil {
-		config.Providers = a.processProviders(config.Providers)
+	// 加载时检查路由引用，尽早发现悬空或拼错的提供商/模型
+	for _, issue := range checkRouterReferences(config) {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: %s: %s", issue.Path, issue.Message)
+		}
 	}
 
-	// Handle Router field
-	if config.Router != nil {
-		config.Router = a.processRouter(config.Router)
+	if a.logger != nil {
+		a.logger.Printf("Successfully loaded config from %s", configPath)
 	}
 
-	return config
+	return config, data, nil
 }
 
-// convertToBool converts various types to bool
-func (a *App) convertToBool(value interface{}) interface{} {
-	switch v := value.(type) {
-	case string:
-		return v == "true"
-	case bool:
-		return v
-	case float64:
-		return v != 0
-	case int:
-		return v != 0
-	default:
-		return false
+// applyConfigDefaults fills in the values CCR assumes when a field is missing
+func (a *App) applyConfigDefaults(config Config) Config {
+	if config.PORT == 0 {
+		config.PORT = defaultPort
 	}
-}
-
-// convertToString converts various types to string
-func (a *App) convertToString(value interface{}) interface{} {
-	switch v := value.(type) {
-	case string:
-		return v
-	case bool:
-		return strconv.FormatBool(v)
-	case float64:
-		return strconv.FormatFloat(v, 'f', -1, 64)
-	case int:
-		return strconv.Itoa(v)
-	default:
-		return fmt.Sprintf("%v", v)
-	}
-}
-
-// convertToInt converts various types to int
-func (a *App) convertToInt(value interface{}, defaultValue int) interface{} {
-	switch v := value.(type) {
-	case string:
-		if timeout, err := strconv.Atoi(v); err == nil {
-			return timeout
-		}
-		return defaultValue
-	case float64:
-		return int(v)
-	case int:
-		return v
-	default:
-		return defaultValue
+	if config.API_TIMEOUT_MS == 0 {
+		config.API_TIMEOUT_MS = defaultAPITimeoutMS
 	}
+	return config
 }
 
-// processProviders handles type conversion for providers
-func (a *App) processProviders(providers interface{}) interface{} {
-	// If it's already a slice of Provider structs, return as is
-	if providerSlice, ok := providers.([]Provider); ok {
-		return providerSlice
-	}
-
-	// If it's a slice of interfaces, process each one
-	if providerSlice, ok := providers.([]interface{}); ok {
-		result := make([]interface{}, len(providerSlice))
-		for i, provider := range providerSlice {
-			if providerMap, ok := provider.(map[string]interface{}); ok {
-				result[i] = a.processProviderMap(providerMap)
-			} else {
-				result[i] = provider
-			}
-		}
-		return result
-	}
-
-	// If it's a slice of maps, process each one
-	if providerSlice, ok := providers.([]map[string]interface{}); ok {
-		result := make([]interface{}, len(providerSlice))
-		for i, providerMap := range providerSlice {
-			result[i] = a.processProviderMap(providerMap)
-		}
-		return result
-	}
-
-	return providers
+// SaveConfig saves the Claude Code Router configuration
+func (a *App) SaveConfig(config Config) error {
+	_, _, err := a.saveConfig(config)
+	return err
 }
 
-// processProviderMap handles type conversion for a single provider map
-func (a *App) processProviderMap(providerMap map[string]interface{}) map[string]interface{} {
-	processed := make(map[string]interface{})
-
-	for key, value := range providerMap {
-		switch key {
-		case "name":
-			processed[key] = a.convertToString(value)
-		case "api_base_url":
-			processed[key] = a.convertToString(value)
-		case "api_key":
-			processed[key] = a.convertToString(value)
-		case "models":
-			processed[key] = a.processModels(value)
-		default:
-			processed[key] = value
+// saveConfig saves config and returns the content it replaced, nil when there
+// was no config, and the content written. Both are read under configMu.
+func (a *App) saveConfig(config Config) ([]byte, []byte, error) {
+	configPath := a.GetConfigPath()
+	if configPath == "" {
+		err := fmt.Errorf("could not determine config path")
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", err)
 		}
+		return nil, nil, err
 	}
 
-	return processed
-}
-
-// processModels handles type conversion for models
-func (a *App) processModels(models interface{}) interface{} {
-	// If it's already a slice of strings, return as is
-	if modelSlice, ok := models.([]string); ok {
-		return modelSlice
-	}
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
 
-	// If it's a slice of interfaces, convert each one to string
-	if modelSlice, ok := models.([]interface{}); ok {
-		result := make([]string, len(modelSlice))
-		for i, model := range modelSlice {
-			result[i] = a.convertToString(model).(string)
+	// 界面上未修改的密钥仍是掩码，还原为已保存的值
+	config, err := unmaskConfigSecrets(config, a.storedConfigForUnmask())
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", err)
 		}
-		return result
+		return nil, nil, err
 	}
 
-	return models
-}
-
-// processRouter handles type conversion for router
-func (a *App) processRouter(router interface{}) interface{} {
-	// If it's already a Router struct, return as is
-	if routerStruct, ok := router.(Router); ok {
-		return routerStruct
-	}
-
-	// If it's a map, process each field
-	if routerMap, ok := router.(map[string]interface{}); ok {
-		processed := make(map[string]interface{})
-
-		for key, value := range routerMap {
-			switch key {
-			case "default", "background", "think", "longContext", "webSearch":
-				processed[key] = a.convertToString(value)
-			case "longContextThreshold":
-				processed[key] = a.convertToInt(value, 60000)
-			default:
-				processed[key] = value
-			}
+	// 磁盘上的配置在加载后被外部修改时，合并双方的修改；存在冲突则拒绝保存
+	config, err = a.reconcileExternalChanges(config)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", err)
 		}
-
-		return processed
+		return nil, nil, err
 	}
 
-	return router
-}
-
-// SaveConfig saves the Claude Code Router configuration
-func (a *App) SaveConfig(config Config) error {
-	configPath := a.GetConfigPath()
-	if configPath == "" {
-		err := fmt.Errorf("could not determine config path")
+	// 保存前校验配置，存在错误时拒绝写入
+	if issues := a.ValidateConfig(config); hasValidationErrors(issues) {
+		err := fmt.Errorf("config is invalid: %s", summarizeIssues(issues))
 		if a.logger != nil {
 			a.logger.Printf("ERROR: %v", err)
 		}
-		return err
+		return nil, nil, err
 	}
 
 	// Create directory if it doesn't exist
@@ -448,7 +394,23 @@ func (a *App) SaveConfig(config Config) error {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: Failed to create directory %s: %v", dir, err)
 		}
-		return err
+		return nil, nil, err
+	}
+
+	// 保留磁盘上已有、但本次保存未包含的未知字段，并沿用原有的键顺序
+	existing, err := os.ReadFile(configPath)
+	if err == nil {
+		var previous Config
+		if err := json.Unmarshal(existing, &previous); err == nil {
+			config.inheritUnknownFields(previous)
+		} else if a.logger != nil {
+			a.logger.Printf("WARNING: Existing config at %s could not be parsed, unknown fields will not be preserved: %v", configPath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to read existing config at %s: %v", configPath, err)
+		}
+		return nil, nil, fmt.Errorf("failed to read current config: %v", err)
 	}
 
 	// Convert to JSON
@@ -457,23 +419,39 @@ func (a *App) SaveConfig(config Config) error {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: Failed to marshal config to JSON: %v", err)
 		}
-		return err
+		return nil, nil, err
+	}
+
+	// 覆盖前备份当前配置
+	if _, err := a.backupConfig(); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to back up config before saving: %v", err)
+		}
+		return nil, nil, err
 	}
 
-	// Write to file
-	err = os.WriteFile(configPath, data, 0644)
+	// Write to a temporary file and rename it over the config
+	err = writeFileAtomic(configPath, data, 0644)
 	if err != nil {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: Failed to write config to %s: %v", configPath, err)
 		}
-		return err
+		return nil, nil, err
 	}
 
+	// 保存后的内容即为新的基准
+	a.loadedConfig = newConfigSnapshot(data)
+
 	if a.logger != nil {
 		a.logger.Printf("Successfully saved config to %s", configPath)
 	}
 
-	return nil
+	// 记录配置历史，失败不影响保存结果
+	if _, err := a.recordRevision(data, ""); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record config revision: %v", err)
+	}
+
+	return existing, data, nil
 }
 
 // ReadREADME reads the README.md file content
@@ -516,6 +494,18 @@ func (a *App) ReadREADME() (string, error) {
 type ServiceStatus struct {
 	IsRunning bool `json:"isRunning"`
 	PID       int  `json:"pid"`
+	// CustomRouter is set when CUSTOM_ROUTER_PATH is configured
+	CustomRouter *CustomRouterStatus `json:"customRouter,omitempty"`
+	// ActiveProfile is the profile last written to config.json, if any
+	ActiveProfile *ActiveProfile `json:"activeProfile,omitempty"`
+	// Health tells whether the port holder is CCR and whether it answers
+	Health HealthProbe `json:"health"`
+	// Process describes the process holding the port, when there is one
+	Process *ProcessInfo `json:"process,omitempty"`
+	// Supervisor reports the service mode and the supervised process
+	Supervisor SupervisorStatus `json:"supervisor"`
+	// Watchdog reports automatic restarts and the crash history
+	Watchdog WatchdogStatus `json:"watchdog"`
 }
 
 // GetServiceStatus checks if the CCR service is running
@@ -523,7 +513,7 @@ func (a *App) GetServiceStatus() (ServiceStatus, error) {
 	var status ServiceStatus
 
 	// 首先获取配置以确定端口号
-	config, err := a.LoadConfig()
+	config, _, err := a.readConfig()
 	if err != nil {
 		if a.logger != nil {
 			a.logger.Printf("ERROR: Failed to load config for service status check: %v", err)
@@ -531,19 +521,16 @@ func (a *App) GetServiceStatus() (ServiceStatus, error) {
 		return status, err
 	}
 
-	// 获取端口号，如果没有配置则使用默认值3456
-	port := 3456
-	if config.PORT != nil {
-		if portVal, ok := config.PORT.(int); ok {
-			port = portVal
-		} else if portVal, ok := config.PORT.(float64); ok {
-			port = int(portVal)
-		} else if portVal, ok := config.PORT.(string); ok {
-			if p, err := strconv.Atoi(portVal); err == nil {
-				port = p
-			}
-		}
+	if config.CUSTOM_ROUTER_PATH != "" {
+		routerStatus := a.customRouterStatus(config)
+		status.CustomRouter = &routerStatus
 	}
+	status.ActiveProfile = a.activeProfileStatus()
+	status.Supervisor = a.GetSupervisorStatus()
+	status.Watchdog = a.GetWatchdogStatus()
+
+	// 获取端口号，LoadConfig 已在未配置时填入默认值3456
+	port := config.PORT
 
 	if a.logger != nil {
 		a.logger.Printf("Checking service status on port %d", port)
@@ -560,10 +547,16 @@ func (a *App) GetServiceStatus() (ServiceStatus, error) {
 
 	status.IsRunning = isRunning
 	status.PID = pid
+	if pid > 0 {
+		status.Process = a.processInfo(pid)
+	}
+
+	// 端口被占用不代表是 CCR，通过 HTTP 确认服务身份和响应情况
+	status.Health = a.probeHealth(config, pid)
 
 	if a.logger != nil {
 		if isRunning {
-			a.logger.Printf("Service is running with PID %d", pid)
+			a.logger.Printf("Service is running with PID %d (%s)", pid, status.Health.State)
 		} else {
 			a.logger.Printf("Service is not running")
 		}
@@ -588,8 +581,33 @@ func (a *App) findProcessByPort(port int) (int, bool, error) {
 	return 0, false, nil
 }
 
-// getProcessIDByPort gets the process ID listening on the specified port
+// errNativePortLookup means the platform has no native port lookup
+var errNativePortLookup = errors.New("native port lookup is not supported")
+
+// getProcessIDByPort gets the process ID listening on the specified port. It
+// returns 0 when nothing listens and an error when that cannot be determined.
 func (a *App) getProcessIDByPort(port int) (int, error) {
+	pid, err := nativeProcessIDByPort(port)
+	if err == nil {
+		return pid, nil
+	}
+	if err != errNativePortLookup && a.logger != nil {
+		a.logger.Printf("WARNING: Native port lookup failed, falling back to external tools: %v", err)
+	}
+
+	pid, toolErr := a.toolProcessIDByPort(port)
+	if toolErr != nil {
+		if err != errNativePortLookup {
+			return 0, fmt.Errorf("%v; %v", err, toolErr)
+		}
+		return 0, toolErr
+	}
+	return pid, nil
+}
+
+// toolProcessIDByPort finds the process listening on port with netstat on
+// Windows or lsof elsewhere
+func (a *App) toolProcessIDByPort(port int) (int, error) {
 	var cmd *exec.Cmd
 
 	// 根据操作系统选择命令
@@ -600,10 +618,16 @@ func (a *App) getProcessIDByPort(port int) (int, error) {
 		// Unix/Linux/macOS: 使用 lsof 命令
 		cmd = exec.Command("lsof", "-i", fmt.Sprintf(":%d", port), "-t")
 	}
+	cmd.SysProcAttr = getSysProcAttr()
 
 	output, err := cmd.Output()
 	if err != nil {
-		return 0, nil
+		// lsof 未找到任何进程时以状态码 1 退出且没有输出
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(strings.TrimSpace(string(output))) == 0 && !isWindows() {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to run %s: %v", filepath.Base(cmd.Path), err)
 	}
 
 	if isWindows() {
@@ -658,16 +682,16 @@ func isWindows() bool {
 // findCCRPath finds the CCR command path based on configuration
 func (a *App) findCCRPath() (string, error) {
 	// 加载配置以获取npm全局安装目录
-	config, err := a.LoadConfig()
+	config, _, err := a.readConfig()
 	if err != nil {
 		return "", fmt.Errorf("failed to load config: %v", err)
 	}
 
 	// 查找ccr命令的绝对路径
 	var ccrPath string
-	if config.NPM_GLOBAL_PREFIX != nil && config.NPM_GLOBAL_PREFIX != "" {
+	if config.NPM_GLOBAL_PREFIX != "" {
 		// 如果配置了npm全局安装目录，优先在此目录中查找ccr
-		npmPrefix := config.NPM_GLOBAL_PREFIX.(string)
+		npmPrefix := config.NPM_GLOBAL_PREFIX
 		ccrPath = filepath.Join(npmPrefix, "ccr")
 		a.logger.Printf("WARNING: CCR command not found in %s", npmPrefix)
 		// 检查文件是否存在
@@ -708,10 +732,23 @@ func (a *App) findCCRPath() (string, error) {
 
 // StartService starts the CCR service
 func (a *App) StartService() error {
+	if err := a.startService(); err != nil {
+		return err
+	}
+	a.watchdogExpect(true)
+	return nil
+}
+
+// startService starts CCR in the configured service mode
+func (a *App) startService() error {
 	if a.logger != nil {
 		a.logger.Printf("Starting CCR service")
 	}
 
+	if a.serviceMode() == ServiceModeSupervised {
+		return a.startSupervised()
+	}
+
 	// 查找ccr命令的绝对路径
 	ccrPath, err := a.findCCRPath()
 	if err != nil {
@@ -726,6 +763,17 @@ func (a *App) StartService() error {
 		a.logger.Printf("Found CCR at path: %s", ccrPath)
 	}
 
+	// 临时写入解析了密钥引用的配置，服务启动后恢复
+	restore, err := a.materializeConfig()
+	if err != nil {
+		errMsg := fmt.Errorf("failed to prepare config for CCR: %v", err)
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", errMsg)
+		}
+		return errMsg
+	}
+	defer restore()
+
 	// 使用 ccr start 命令启动服务
 	cmd := exec.Command(ccrPath, "start")
 
@@ -784,6 +832,14 @@ func (a *App) StopService() error {
 		a.logger.Printf("Stopping CCR service")
 	}
 
+	// 主动停止的服务不再由看门狗重启
+	a.watchdogExpect(false)
+
+	// 由管理器启动的进程直接停止，与当前模式无关
+	if a.supervisorActive() {
+		return a.stopSupervised()
+	}
+
 	// 查找ccr命令的绝对路径
 	ccrPath, err := a.findCCRPath()
 	if err != nil {
@@ -852,10 +908,35 @@ func (a *App) StopService() error {
 
 // RestartService restarts the CCR service
 func (a *App) RestartService() error {
+	if err := a.restartService(); err != nil {
+		return err
+	}
+	a.watchdogExpect(true)
+	return nil
+}
+
+// restartService restarts CCR in the configured service mode
+func (a *App) restartService() error {
 	if a.logger != nil {
 		a.logger.Printf("Restarting CCR service")
 	}
 
+	if a.serviceMode() == ServiceModeSupervised {
+		if a.supervisorActive() {
+			if err := a.stopSupervised(); err != nil {
+				return err
+			}
+		} else if config, _, err := a.readConfig(); err == nil {
+			// 以命令行模式启动的实例先通过 ccr stop 停止
+			if _, running, _ := a.findProcessByPort(config.PORT); running {
+				if err := a.StopService(); err != nil {
+					return err
+				}
+			}
+		}
+		return a.startSupervised()
+	}
+
 	// 查找ccr命令的绝对路径
 	ccrPath, err := a.findCCRPath()
 	if err != nil {
@@ -870,6 +951,17 @@ func (a *App) RestartService() error {
 		a.logger.Printf("Found CCR at path: %s", ccrPath)
 	}
 
+	// 临时写入解析了密钥引用的配置，服务启动后恢复
+	restore, err := a.materializeConfig()
+	if err != nil {
+		errMsg := fmt.Errorf("failed to prepare config for CCR: %v", err)
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", errMsg)
+		}
+		return errMsg
+	}
+	defer restore()
+
 	// 使用 ccr restart 命令重启服务
 	cmd := exec.Command(ccrPath, "restart")
 
@@ -1114,7 +1206,7 @@ func (a *App) ReadLogs() (string, error) {
 			a.logger.Printf("Successfully read %d lines from log file", len(lines))
 		}
 
-		return strings.Join(lines, "\n"), nil
+		return redactKnownSecrets(strings.Join(lines, "\n"), a.knownSecretValues()), nil
 	}
 
 	// 文件较小，直接读取
@@ -1143,7 +1235,7 @@ func (a *App) ReadLogs() (string, error) {
 		a.logger.Printf("Successfully read %d lines from log file", len(lines))
 	}
 
-	return strings.Join(lines, "\n"), nil
+	return redactKnownSecrets(strings.Join(lines, "\n"), a.knownSecretValues()), nil
 }
 
 // ClearLogs clears the CCR log file
@@ -1314,7 +1406,7 @@ func (a *App) ReadAppLogs() (string, error) {
 			a.logger.Printf("Successfully read %d lines from app log file", len(lines))
 		}
 
-		return strings.Join(lines, "\n"), nil
+		return redactKnownSecrets(strings.Join(lines, "\n"), a.knownSecretValues()), nil
 	}
 
 	// 文件较小，直接读取
@@ -1343,7 +1435,7 @@ func (a *App) ReadAppLogs() (string, error) {
 		a.logger.Printf("Successfully read %d lines from app log file", len(lines))
 	}
 
-	return strings.Join(lines, "\n"), nil
+	return redactKnownSecrets(strings.Join(lines, "\n"), a.knownSecretValues()), nil
 }
 
 // GetAppVersion returns the version of the application
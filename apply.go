@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// applyHealthTimeout is how long CCR may take to become healthy after
+	// ApplyConfig restarted it
+	applyHealthTimeout = 20 * time.Second
+	// applyPollInterval is how often the health probe runs while waiting
+	applyPollInterval = 500 * time.Millisecond
+)
+
+// Outcomes of ApplyConfig
+const (
+	// ApplyApplied means the new config was saved and CCR is healthy with it
+	ApplyApplied = "applied"
+	// ApplyRejected means the config was not saved, nothing changed
+	ApplyRejected = "rejected"
+	// ApplyRolledBack means CCR failed with the new config and is healthy
+	// again with the previous one
+	ApplyRolledBack = "rolled-back"
+	// ApplyRollbackFailed means the previous config was restored but CCR
+	// did not come up with it either
+	ApplyRollbackFailed = "rollback-failed"
+	// ApplyFailed means CCR failed with the new config and there was no
+	// previous config to restore
+	ApplyFailed = "failed"
+)
+
+// Step results of an ApplyReport
+const (
+	StepOK      = "ok"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// ApplyStep is one step taken by ApplyConfig
+type ApplyStep struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// ApplyReport describes what ApplyConfig did
+type ApplyReport struct {
+	Outcome string            `json:"outcome"`
+	Issues  []ValidationIssue `json:"issues"`
+	// Changes are the differences between the previous and the new config
+	Changes []ConfigChange `json:"changes"`
+	Steps   []ApplyStep    `json:"steps"`
+	// Health is the last probe of CCR
+	Health HealthProbe `json:"health"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// addStep records the result of a step started at start
+func (r *ApplyReport) addStep(name string, start time.Time, err error) {
+	step := ApplyStep{Name: name, Status: StepOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		step.Status = StepFailed
+		step.Message = redactSecrets(err.Error())
+	}
+	r.Steps = append(r.Steps, step)
+}
+
+// skipStep records a step that was not taken
+func (r *ApplyReport) skipStep(name, reason string) {
+	r.Steps = append(r.Steps, ApplyStep{Name: name, Status: StepSkipped, Message: reason})
+}
+
+// ccrInstance identifies the CCR process that ran before a restart
+type ccrInstance struct {
+	pid int
+	// pidFileAt is when the PID file was written, zero when there was none
+	pidFileAt time.Time
+}
+
+// currentCCRInstance records the process listening on the configured port
+// and the PID file CCR wrote when it started
+func (a *App) currentCCRInstance() ccrInstance {
+	port := 0
+	if config, _, err := a.readConfig(); err == nil {
+		port = config.PORT
+	}
+	return a.ccrInstanceOnPort(port)
+}
+
+// ccrInstanceOnPort records the process listening on port and the PID file
+// CCR wrote when it started
+func (a *App) ccrInstanceOnPort(port int) ccrInstance {
+	var instance ccrInstance
+	if port > 0 {
+		instance.pid, _, _ = a.findProcessByPort(port)
+	}
+	if _, writtenAt, ok := a.readCCRPIDFile(); ok {
+		instance.pidFileAt = writtenAt
+	}
+	return instance
+}
+
+// isNewInstance reports whether the process with pid was started after
+// previous was recorded. When either PID is unknown the PID file decides.
+func (a *App) isNewInstance(previous ccrInstance, pid int) bool {
+	if pid > 0 && previous.pid > 0 {
+		return pid != previous.pid
+	}
+	_, writtenAt, ok := a.readCCRPIDFile()
+	return !ok || previous.pidFileAt.IsZero() || writtenAt.After(previous.pidFileAt)
+}
+
+// waitHealthy probes CCR until a process other than previous is healthy or
+// timeout passes. A supervised process that exits ends the wait early.
+func (a *App) waitHealthy(timeout time.Duration, previous ccrInstance) (HealthProbe, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var probe HealthProbe
+		config, _, err := a.readConfig()
+		if err != nil {
+			return probe, fmt.Errorf("failed to load config: %v", err)
+		}
+		pid, _, _ := a.findProcessByPort(config.PORT)
+		probe = a.probeHealth(config, pid)
+		// 旧进程尚未退出时仍可能通过健康检查，不能当作重启成功
+		restarted := a.isNewInstance(previous, pid)
+		if probe.State == HealthHealthy && restarted {
+			return probe, nil
+		}
+		// 新进程拒绝了配置的 APIKEY，继续等待也不会恢复
+		if probe.State == HealthAuthFailed && restarted {
+			return probe, fmt.Errorf("CCR is running but rejects the configured APIKEY with HTTP %d", probe.StatusCode)
+		}
+
+		if status := a.GetSupervisorStatus(); status.Mode == ServiceModeSupervised && status.State == SupervisorCrashed {
+			return probe, fmt.Errorf("CCR exited with code %d: %s", status.ExitCode, status.Error)
+		}
+		if time.Now().After(deadline) {
+			err := fmt.Errorf("CCR was not healthy within %v, state %s", timeout, probe.State)
+			if probe.State == HealthHealthy {
+				err = fmt.Errorf("CCR was not restarted within %v, the process that ran before is still serving", timeout)
+			} else if probe.Message != "" {
+				err = fmt.Errorf("%v: %s", err, probe.Message)
+			}
+			return probe, err
+		}
+		time.Sleep(applyPollInterval)
+	}
+}
+
+// restartAndWait restarts CCR and waits for it to become healthy, recording
+// both steps in report
+func (a *App) restartAndWait(report *ApplyReport, restartStep, healthStep string) error {
+	previous := a.currentCCRInstance()
+
+	start := time.Now()
+	if err := a.RestartService(); err != nil {
+		report.addStep(restartStep, start, err)
+		report.skipStep(healthStep, "CCR did not restart")
+		return err
+	}
+	report.addStep(restartStep, start, nil)
+
+	start = time.Now()
+	probe, err := a.waitHealthy(applyHealthTimeout, previous)
+	report.Health = probe
+	report.addStep(healthStep, start, err)
+	return err
+}
+
+// restorePreviousConfig writes back the config.json that was replaced by
+// ApplyConfig
+func (a *App) restorePreviousConfig(data []byte) error {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	if err := writeFileAtomic(a.GetConfigPath(), data, 0644); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to restore previous config: %v", err)
+		}
+		return fmt.Errorf("failed to restore previous config: %v", err)
+	}
+	a.loadedConfig = newConfigSnapshot(data)
+
+	if _, err := a.recordRevision(data, "rolled back failed apply"); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record config revision: %v", err)
+	}
+	return nil
+}
+
+// ApplyConfig saves config, restarts CCR and waits for it to become healthy.
+// If CCR fails with the new config, the previous config is restored and CCR
+// restarted again. Errors are reported in the returned report, the error is
+// only set when nothing could be attempted.
+func (a *App) ApplyConfig(config Config) (ApplyReport, error) {
+	report := ApplyReport{Issues: []ValidationIssue{}, Changes: []ConfigChange{}, Steps: []ApplyStep{}}
+
+	if !a.applyMu.TryLock() {
+		return report, fmt.Errorf("another config is being applied")
+	}
+	defer a.applyMu.Unlock()
+
+	if a.GetConfigPath() == "" {
+		return report, fmt.Errorf("could not determine config path")
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Applying config")
+	}
+
+	start := time.Now()
+	report.Issues = a.ValidateConfig(config)
+	if hasValidationErrors(report.Issues) {
+		err := fmt.Errorf("config is invalid: %s", summarizeIssues(report.Issues))
+		report.addStep("validate", start, err)
+		report.Outcome = ApplyRejected
+		report.Error = err.Error()
+		return report, nil
+	}
+	report.addStep("validate", start, nil)
+
+	// 被替换的内容在保存时于同一把锁下读取，用于回滚
+	start = time.Now()
+	previous, saved, err := a.saveConfig(config)
+	if err != nil {
+		report.addStep("save", start, err)
+		report.Outcome = ApplyRejected
+		report.Error = redactSecrets(err.Error())
+		return report, nil
+	}
+	report.addStep("save", start, nil)
+
+	var before, after Config
+	if previous != nil {
+		json.Unmarshal(previous, &before)
+	}
+	if err := json.Unmarshal(saved, &after); err == nil {
+		report.Changes = diffConfigs(a.applyConfigDefaults(before), a.applyConfigDefaults(after))
+	}
+
+	applyErr := a.restartAndWait(&report, "restart", "health")
+	if applyErr == nil {
+		report.Outcome = ApplyApplied
+		if a.logger != nil {
+			a.logger.Printf("Applied config, CCR is healthy")
+		}
+		return report, nil
+	}
+	report.Error = redactSecrets(applyErr.Error())
+
+	if a.logger != nil {
+		a.logger.Printf("ERROR: CCR failed with the new config, rolling back: %v", applyErr)
+	}
+
+	// 停止看门狗对失败配置的重启，回滚后重新启动时恢复监控
+	a.watchdogExpect(false)
+
+	if previous == nil {
+		report.skipStep("rollback", "there was no previous config")
+		report.Outcome = ApplyFailed
+		return report, nil
+	}
+
+	start = time.Now()
+	if err := a.restorePreviousConfig(previous); err != nil {
+		report.addStep("rollback", start, err)
+		report.Outcome = ApplyRollbackFailed
+		return report, nil
+	}
+	report.addStep("rollback", start, nil)
+
+	if err := a.restartAndWait(&report, "restart previous", "health previous"); err != nil {
+		report.Outcome = ApplyRollbackFailed
+		if a.logger != nil {
+			a.logger.Printf("ERROR: CCR failed with the previous config too: %v", err)
+		}
+		return report, nil
+	}
+
+	report.Outcome = ApplyRolledBack
+	if a.logger != nil {
+		a.logger.Printf("Rolled back to the previous config, CCR is healthy")
+	}
+	return report, nil
+}
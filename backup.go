@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix     = "config-"
+	backupSuffix     = ".json"
+	backupTimeLayout = "20060102-150405.000"
+)
+
+// ConfigBackup describes a saved copy of config.json
+type ConfigBackup struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"createdAt"`
+	Size      int64  `json:"size"`
+}
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it and
+// renames it over path, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	// 出错时清理临时文件
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %v", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set file permissions: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	success = true
+
+	// 同步目录以持久化重命名操作；Windows 不支持打开目录，忽略错误
+	if dirFile, err := os.Open(dir); err == nil {
+		dirFile.Sync()
+		dirFile.Close()
+	}
+
+	return nil
+}
+
+// GetBackupDir returns the directory holding config backups
+func (a *App) GetBackupDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "backups")
+}
+
+// backupConfig copies the current config.json into the backup directory and
+// prunes old backups. It returns the ID of the new backup, or "" when there
+// was no config to back up.
+func (a *App) backupConfig() (string, error) {
+	configPath := a.GetConfigPath()
+	backupDir := a.GetBackupDir()
+	if configPath == "" || backupDir == "" {
+		return "", fmt.Errorf("could not determine backup path")
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read config for backup: %v", err)
+	}
+
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	// 同一毫秒内多次备份时追加序号，避免覆盖
+	stamp := time.Now().Format(backupTimeLayout)
+	id := backupPrefix + stamp + backupSuffix
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(backupDir, id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s%s-%d%s", backupPrefix, stamp, i, backupSuffix)
+	}
+
+	if err := writeFileAtomic(filepath.Join(backupDir, id), data, 0600); err != nil {
+		return "", err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Backed up config to %s", id)
+	}
+
+	a.pruneBackups()
+
+	return id, nil
+}
+
+// pruneBackups removes the oldest backups beyond the configured retention
+func (a *App) pruneBackups() {
+	settings, err := a.GetManagerSettings()
+	if err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Using default backup retention: %v", err)
+	}
+
+	backups, err := a.ListConfigBackups()
+	if err != nil {
+		return
+	}
+
+	for _, backup := range backups[min(len(backups), settings.BackupRetention):] {
+		if err := os.Remove(filepath.Join(a.GetBackupDir(), backup.ID)); err != nil {
+			if a.logger != nil {
+				a.logger.Printf("WARNING: Failed to remove old backup %s: %v", backup.ID, err)
+			}
+		}
+	}
+}
+
+// ListConfigBackups lists the available config backups, newest first
+func (a *App) ListConfigBackups() ([]ConfigBackup, error) {
+	backups := []ConfigBackup{}
+
+	backupDir := a.GetBackupDir()
+	if backupDir == "" {
+		return backups, fmt.Errorf("could not determine backup path")
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return backups, nil
+	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to list backups in %s: %v", backupDir, err)
+		}
+		return backups, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		createdAt := info.ModTime()
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+		if len(stamp) >= len(backupTimeLayout) {
+			if parsed, err := time.ParseInLocation(backupTimeLayout, stamp[:len(backupTimeLayout)], time.Local); err == nil {
+				createdAt = parsed
+			}
+		}
+
+		backups = append(backups, ConfigBackup{
+			ID:        name,
+			CreatedAt: createdAt.Format(time.RFC3339),
+			Size:      info.Size(),
+		})
+	}
+
+	// 文件名包含时间戳，按名称倒序即为由新到旧
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID > backups[j].ID
+	})
+
+	return backups, nil
+}
+
+// RestoreConfigBackup replaces config.json with the given backup. The current
+// config is backed up first so the restore itself can be undone.
+func (a *App) RestoreConfigBackup(id string) error {
+	if id == "" || id != filepath.Base(id) || !strings.HasPrefix(id, backupPrefix) || !strings.HasSuffix(id, backupSuffix) {
+		return fmt.Errorf("invalid backup id: %q", id)
+	}
+
+	backupPath := filepath.Join(a.GetBackupDir(), id)
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to read backup %s: %v", id, err)
+		}
+		return fmt.Errorf("failed to read backup: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("backup %s is not a valid config: %v", id, err)
+	}
+
+	// 与保存互斥，也避免在密钥临时写入磁盘期间备份或覆盖配置
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	if _, err := a.backupConfig(); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to back up config before restore: %v", err)
+		}
+		return err
+	}
+
+	configPath := a.GetConfigPath()
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to restore backup %s: %v", id, err)
+		}
+		return err
+	}
+	// 恢复后的内容即为新的基准，界面随后重新加载
+	a.loadedConfig = newConfigSnapshot(data)
+
+	if a.logger != nil {
+		a.logger.Printf("Restored config from backup %s", id)
+	}
+
+	if _, err := a.recordRevision(data, "restored from backup "+id); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record config revision: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,965 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	bundleVersion       = 1
+	bundleManifestEntry = "manifest.json"
+	bundleConfigEntry   = "config.json"
+	bundleSecretsEntry  = "secrets.enc"
+	bundlePluginsPrefix = "plugins/"
+	bundleRouterPrefix  = "router/"
+	bundlePresetsPrefix = "presets/"
+	bundleTimeLayout    = "20060102-150405"
+	maxBundleEntrySize  = 8 << 20
+	bundleAPIKeyPath    = "APIKEY"
+	bundleProxyPath     = "PROXY_URL"
+)
+
+// How secrets are written into an exported bundle
+const (
+	// SecretsStrip removes secrets from the bundle
+	SecretsStrip = "strip"
+	// SecretsEnv replaces secrets with ${CCR_...} environment references
+	SecretsEnv = "env"
+	// SecretsEncrypt removes secrets from config.json and stores them
+	// encrypted with a passphrase in secrets.enc
+	SecretsEncrypt = "encrypt"
+)
+
+// Status of a bundle item compared with the current config
+const (
+	ImportNew       = "new"
+	ImportChanged   = "changed"
+	ImportUnchanged = "unchanged"
+)
+
+// envPlaceholderInvalid matches characters not allowed in a variable name
+var envPlaceholderInvalid = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// ExportOptions selects what goes into an exported bundle
+type ExportOptions struct {
+	// Path is where the bundle is written, empty for the exports directory
+	Path                string `json:"path"`
+	IncludePresets      bool   `json:"includePresets"`
+	IncludePlugins      bool   `json:"includePlugins"`
+	IncludeCustomRouter bool   `json:"includeCustomRouter"`
+	// Secrets is one of SecretsStrip, SecretsEnv or SecretsEncrypt
+	Secrets    string `json:"secrets"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// ExportResult describes a written bundle
+type ExportResult struct {
+	Path string `json:"path"`
+	// Secrets lists the fields whose secrets were stripped, replaced or
+	// encrypted
+	Secrets []string `json:"secrets"`
+	Files   []string `json:"files"`
+}
+
+// ImportSelection picks the parts of a bundle to bring into the config.
+// HOST, PORT and NPM_GLOBAL_PREFIX describe the local machine and are never
+// imported.
+type ImportSelection struct {
+	Providers []string `json:"providers"`
+	Slots     []string `json:"slots"`
+	// Settings imports APIKEY, PROXY_URL, API_TIMEOUT_MS and LOG
+	Settings     bool `json:"settings"`
+	Plugins      bool `json:"plugins"`
+	CustomRouter bool `json:"customRouter"`
+	Presets      bool `json:"presets"`
+	// Passphrase decrypts the secrets of an encrypted bundle. Without it the
+	// secrets are skipped.
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// ImportItem compares a provider or Router slot of a bundle with the config
+type ImportItem struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// ImportFile describes a file of a bundle and where it would be written
+type ImportFile struct {
+	Entry   string `json:"entry"`
+	Target  string `json:"target"`
+	Exists  bool   `json:"exists"`
+	Changed bool   `json:"changed"`
+}
+
+// ImportPreview shows what importing a bundle would change
+type ImportPreview struct {
+	Path         string       `json:"path"`
+	CreatedAt    string       `json:"createdAt"`
+	Secrets      string       `json:"secrets"`
+	Encrypted    bool         `json:"encrypted"`
+	Providers    []ImportItem `json:"providers"`
+	Slots        []ImportItem `json:"slots"`
+	Plugins      []ImportFile `json:"plugins"`
+	CustomRouter *ImportFile  `json:"customRouter,omitempty"`
+	Presets      []ImportFile `json:"presets"`
+	// Changes is the diff of importing everything in the bundle
+	Changes []ConfigChange `json:"changes"`
+	// Issues lists the validation errors importing everything would cause
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// bundleManifest is manifest.json of a bundle
+type bundleManifest struct {
+	Version   int    `json:"version"`
+	CreatedAt string `json:"createdAt"`
+	Secrets   string `json:"secrets"`
+	// Plugins maps transformer plugin paths in config.json to bundle entries
+	Plugins      []bundleFile `json:"plugins,omitempty"`
+	CustomRouter *bundleFile  `json:"customRouter,omitempty"`
+	Presets      []string     `json:"presets,omitempty"`
+}
+
+// bundleFile is a script stored in a bundle together with the path the
+// exporting config used for it
+type bundleFile struct {
+	Path  string `json:"path"`
+	Entry string `json:"entry"`
+}
+
+// bundle is a bundle read into memory
+type bundle struct {
+	manifest bundleManifest
+	config   Config
+	entries  map[string][]byte
+}
+
+// GetExportsDir returns the directory exported bundles are written to by
+// default
+func (a *App) GetExportsDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "exports")
+}
+
+// GetPluginsDir returns the directory imported transformer plugins are
+// written to
+func (a *App) GetPluginsDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "plugins")
+}
+
+// GetRoutersDir returns the directory imported custom router scripts are
+// written to
+func (a *App) GetRoutersDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "routers")
+}
+
+// validScriptEntry reports whether entry is a script directly below prefix,
+// so it can only be written to the directory meant for it
+func validScriptEntry(entry, prefix string) bool {
+	name := strings.TrimPrefix(entry, prefix)
+	return strings.HasPrefix(entry, prefix) && name != "" && !strings.Contains(name, "/") && strings.HasSuffix(name, ".js")
+}
+
+// envPlaceholder returns the ${CCR_...} reference that replaces the secret
+// at path when exporting with SecretsEnv
+func envPlaceholder(name string) string {
+	name = envPlaceholderInvalid.ReplaceAllString(strings.ToUpper(name), "_")
+	return "${CCR_" + strings.Trim(name, "_") + "}"
+}
+
+// providerSecretPath is the secrets.enc key of a provider's api_key
+func providerSecretPath(name string) string {
+	return "Providers." + name + ".api_key"
+}
+
+// presetSecretPath is the secrets.enc key of a preset's api_key
+func presetSecretPath(id string) string {
+	return "presets." + id + ".api_key"
+}
+
+// hasSecret reports whether value is a secret that must not leave the
+// machine as written: a literal or a vault reference. Environment references
+// are portable and kept.
+func hasSecret(value string) bool {
+	return value != "" && len(envRefNames(value)) == 0
+}
+
+// stripProxyCredentials removes the user info of a proxy URL, reporting
+// whether it held a password
+func stripProxyCredentials(proxy string) (string, bool) {
+	parsed, err := url.Parse(proxy)
+	if err != nil || parsed.User == nil {
+		return proxy, false
+	}
+	if password, ok := parsed.User.Password(); !ok || !hasSecret(password) {
+		return proxy, false
+	}
+	parsed.User = nil
+	return parsed.String(), true
+}
+
+// sanitizeBundle removes the secrets of config and presets according to
+// mode. It returns the removed values by path for SecretsEncrypt, and the
+// paths that were handled.
+func sanitizeBundle(config Config, presets []ProviderPreset, mode string) (Config, []ProviderPreset, map[string]string, []string) {
+	secrets := make(map[string]string)
+	handled := []string{}
+
+	// replace 按模式替换密钥；cleared 为去除密钥后的值
+	replace := func(path, value, cleared, placeholder string) string {
+		handled = append(handled, path)
+		switch mode {
+		case SecretsEnv:
+			return placeholder
+		case SecretsEncrypt:
+			secrets[path] = value
+		}
+		return cleared
+	}
+
+	if hasSecret(config.APIKEY) {
+		config.APIKEY = replace(bundleAPIKeyPath, config.APIKEY, "", envPlaceholder("APIKEY"))
+	}
+	if cleared, ok := stripProxyCredentials(config.PROXY_URL); ok {
+		config.PROXY_URL = replace(bundleProxyPath, config.PROXY_URL, cleared, envPlaceholder("PROXY_URL"))
+	}
+
+	providers := make([]Provider, len(config.Providers))
+	copy(providers, config.Providers)
+	for i := range providers {
+		if hasSecret(providers[i].APIKey) {
+			name := providers[i].Name
+			providers[i].APIKey = replace(providerSecretPath(name), providers[i].APIKey, "", envPlaceholder(name+"_API_KEY"))
+		}
+	}
+	config.Providers = providers
+
+	sanitized := make([]ProviderPreset, len(presets))
+	copy(sanitized, presets)
+	for i := range sanitized {
+		preset := &sanitized[i]
+		preset.Source = ""
+		if !hasSecret(preset.APIKey) {
+			continue
+		}
+		placeholder := envPlaceholder(preset.ID + "_API_KEY")
+		preset.APIKey = replace(presetSecretPath(preset.ID), preset.APIKey, "", "")
+		if mode == SecretsEnv {
+			// 模板通过 api_key_env 引用环境变量
+			preset.APIKeyEnv = strings.TrimSuffix(strings.TrimPrefix(placeholder, "${"), "}")
+		}
+	}
+
+	return config, sanitized, secrets, handled
+}
+
+// addBundleEntry writes a single file into the bundle archive
+func addBundleEntry(w *zip.Writer, name string, data []byte) error {
+	f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// ExportConfig packages the saved config, and optionally the user provider
+// presets, transformer plugin files and custom router script, into a zip
+// bundle. Secrets are handled as options.Secrets says; values that already
+// refer to environment variables are kept.
+func (a *App) ExportConfig(options ExportOptions) (ExportResult, error) {
+	result := ExportResult{Secrets: []string{}, Files: []string{}}
+
+	switch options.Secrets {
+	case SecretsStrip, SecretsEnv:
+	case SecretsEncrypt:
+		if len(options.Passphrase) < minPassphraseLength {
+			return result, fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength)
+		}
+	default:
+		return result, fmt.Errorf("unknown secrets mode %q", options.Secrets)
+	}
+
+	// 持有配置锁读取，避免导出服务启动时临时写入的明文密钥
+	a.configMu.Lock()
+	data, err := os.ReadFile(a.GetConfigPath())
+	a.configMu.Unlock()
+	if err != nil {
+		return result, fmt.Errorf("failed to read config: %v", err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return result, fmt.Errorf("failed to parse config: %v", err)
+	}
+
+	// 加密导出时写入密钥库中的实际值，其他模式下引用会被替换
+	if options.Secrets == SecretsEncrypt && hasVaultRefs(config) {
+		if config, err = a.resolveVaultRefs(config); err != nil {
+			return result, err
+		}
+	}
+
+	var presets []ProviderPreset
+	if options.IncludePresets {
+		presets = a.loadUserPresets()
+	}
+
+	config, presets, secrets, handled := sanitizeBundle(config, presets, options.Secrets)
+	result.Secrets = handled
+
+	manifest := bundleManifest{
+		Version:   bundleVersion,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Secrets:   options.Secrets,
+	}
+	files := make(map[string][]byte)
+
+	// addScript 读取脚本文件并分配归档内的名称
+	addScript := func(prefix, scriptPath string) (bundleFile, error) {
+		resolved := resolveScriptPath(scriptPath)
+		content, err := os.ReadFile(resolved)
+		if err != nil {
+			return bundleFile{}, fmt.Errorf("failed to read %s: %v", resolved, err)
+		}
+		entry := prefix + filepath.Base(resolved)
+		if !validScriptEntry(entry, prefix) {
+			return bundleFile{}, fmt.Errorf("%s is not a .js script", resolved)
+		}
+		if _, exists := files[entry]; exists {
+			return bundleFile{}, fmt.Errorf("more than one file is named %s", filepath.Base(resolved))
+		}
+		files[entry] = content
+		return bundleFile{Path: scriptPath, Entry: entry}, nil
+	}
+
+	if options.IncludePlugins {
+		for _, plugin := range config.Transformers {
+			file, err := addScript(bundlePluginsPrefix, plugin.Path)
+			if err != nil {
+				return result, err
+			}
+			manifest.Plugins = append(manifest.Plugins, file)
+		}
+	}
+	if options.IncludeCustomRouter && config.CUSTOM_ROUTER_PATH != "" {
+		file, err := addScript(bundleRouterPrefix, config.CUSTOM_ROUTER_PATH)
+		if err != nil {
+			return result, err
+		}
+		manifest.CustomRouter = &file
+	}
+	for _, preset := range presets {
+		content, err := json.MarshalIndent(preset, "", "  ")
+		if err != nil {
+			return result, err
+		}
+		entry := bundlePresetsPrefix + preset.ID + ".json"
+		files[entry] = content
+		manifest.Presets = append(manifest.Presets, entry)
+	}
+
+	if len(secrets) > 0 {
+		entries := make(map[string]vaultEntry, len(secrets))
+		for secretPath, value := range secrets {
+			entries[secretPath] = vaultEntry{Value: value}
+		}
+		v, err := newUnlockedVault(options.Passphrase, entries)
+		if err != nil {
+			return result, err
+		}
+		if files[bundleSecretsEntry], err = v.seal(); err != nil {
+			return result, fmt.Errorf("failed to encrypt secrets: %v", err)
+		}
+	}
+
+	configData, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return result, err
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if err := addBundleEntry(w, bundleManifestEntry, manifestData); err != nil {
+		return result, err
+	}
+	if err := addBundleEntry(w, bundleConfigEntry, configData); err != nil {
+		return result, err
+	}
+	for _, name := range sortedKeys(files) {
+		if err := addBundleEntry(w, name, files[name]); err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, name)
+	}
+	if err := w.Close(); err != nil {
+		return result, err
+	}
+
+	result.Path = strings.TrimSpace(options.Path)
+	if result.Path == "" {
+		exportsDir := a.GetExportsDir()
+		if exportsDir == "" {
+			return result, fmt.Errorf("could not determine exports path")
+		}
+		result.Path = filepath.Join(exportsDir, "ccr-bundle-"+time.Now().Format(bundleTimeLayout)+".zip")
+	}
+	if err := os.MkdirAll(filepath.Dir(result.Path), 0755); err != nil {
+		return result, fmt.Errorf("failed to create directory: %v", err)
+	}
+	// 脚本和配置中的其他字段仍可能含有敏感信息，仅允许当前用户读取
+	if err := writeFileAtomic(result.Path, buf.Bytes(), 0600); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write bundle %s: %v", result.Path, err)
+		}
+		return result, err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Exported config bundle to %s (secrets: %s)", result.Path, options.Secrets)
+	}
+	return result, nil
+}
+
+// sortedKeys lists the keys of a file map, sorted
+func sortedKeys(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readBundle reads and checks a bundle archive
+func readBundle(bundlePath string) (*bundle, error) {
+	r, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %v", err)
+	}
+	defer r.Close()
+
+	b := &bundle{entries: make(map[string][]byte)}
+	for _, f := range r.File {
+		// 只接受规范的相对路径，忽略目录项
+		if f.FileInfo().IsDir() || path.Clean(f.Name) != f.Name || strings.HasPrefix(f.Name, "/") || strings.HasPrefix(f.Name, "../") {
+			continue
+		}
+		if f.UncompressedSize64 > maxBundleEntrySize {
+			return nil, fmt.Errorf("bundle entry %s is too large", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle entry %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxBundleEntrySize+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle entry %s: %v", f.Name, err)
+		}
+		if len(data) > maxBundleEntrySize {
+			return nil, fmt.Errorf("bundle entry %s is too large", f.Name)
+		}
+		b.entries[f.Name] = data
+	}
+
+	manifestData, ok := b.entries[bundleManifestEntry]
+	if !ok {
+		return nil, fmt.Errorf("not a config bundle: %s is missing", bundleManifestEntry)
+	}
+	if err := json.Unmarshal(manifestData, &b.manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %v", err)
+	}
+	if b.manifest.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", b.manifest.Version)
+	}
+
+	configData, ok := b.entries[bundleConfigEntry]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no %s", bundleConfigEntry)
+	}
+	if err := json.Unmarshal(configData, &b.config); err != nil {
+		return nil, fmt.Errorf("bundle config is not valid: %v", err)
+	}
+
+	for _, file := range b.manifest.Plugins {
+		if _, ok := b.entries[file.Entry]; !ok || !validScriptEntry(file.Entry, bundlePluginsPrefix) {
+			return nil, fmt.Errorf("bundle plugin %s is missing or misnamed", file.Entry)
+		}
+	}
+	if file := b.manifest.CustomRouter; file != nil {
+		if _, ok := b.entries[file.Entry]; !ok || !validScriptEntry(file.Entry, bundleRouterPrefix) {
+			return nil, fmt.Errorf("bundle custom router %s is missing or misnamed", file.Entry)
+		}
+	}
+	for _, entry := range b.manifest.Presets {
+		if _, ok := b.entries[entry]; !ok || !strings.HasPrefix(entry, bundlePresetsPrefix) || !strings.HasSuffix(entry, ".json") {
+			return nil, fmt.Errorf("bundle preset %s is missing or misnamed", entry)
+		}
+	}
+
+	return b, nil
+}
+
+// fileTarget describes where a bundle file would be written
+func (b *bundle) fileTarget(entry, target string) ImportFile {
+	file := ImportFile{Entry: entry, Target: target}
+	if existing, err := os.ReadFile(target); err == nil {
+		file.Exists = true
+		file.Changed = !bytes.Equal(existing, b.entries[entry])
+	}
+	return file
+}
+
+// importFiles lists where the scripts and presets of b would be written
+func (a *App) importFiles(b *bundle) ([]ImportFile, *ImportFile, []ImportFile) {
+	plugins := []ImportFile{}
+	for _, file := range b.manifest.Plugins {
+		plugins = append(plugins, b.fileTarget(file.Entry, filepath.Join(a.GetPluginsDir(), path.Base(file.Entry))))
+	}
+
+	var router *ImportFile
+	if file := b.manifest.CustomRouter; file != nil {
+		target := b.fileTarget(file.Entry, filepath.Join(a.GetRoutersDir(), path.Base(file.Entry)))
+		router = &target
+	}
+
+	presets := []ImportFile{}
+	for _, entry := range b.manifest.Presets {
+		presets = append(presets, b.fileTarget(entry, filepath.Join(a.GetPresetsDir(), path.Base(entry))))
+	}
+
+	return plugins, router, presets
+}
+
+// keepsLocalSecret reports whether an imported secret field should keep the
+// local value: the bundle carries no secret for it, only an empty value or
+// the placeholder written on export
+func keepsLocalSecret(imported, placeholder string, manifest bundleManifest) bool {
+	return imported == "" || (manifest.Secrets == SecretsEnv && imported == placeholder)
+}
+
+// keepsProxyCredentials reports whether imported is the local proxy with its
+// credentials stripped, so the local URL should be kept
+func keepsProxyCredentials(imported, local string) bool {
+	cleared, ok := stripProxyCredentials(local)
+	return ok && imported == cleared
+}
+
+// mergeBundle applies the selected parts of b to current. secrets holds the
+// decrypted secrets of an encrypted bundle; pluginTargets and routerTarget
+// map bundle files to where they are written.
+func mergeBundle(current Config, b *bundle, selection ImportSelection, secrets map[string]string, pluginTargets map[string]string, routerTarget string) Config {
+	imported := b.config
+
+	// secret 取导入的密钥，缺少时保留本地值
+	secret := func(secretPath, value, local, placeholder string) string {
+		if decrypted, ok := secrets[secretPath]; ok {
+			return decrypted
+		}
+		if local != "" && keepsLocalSecret(value, placeholder, b.manifest) {
+			return local
+		}
+		return value
+	}
+
+	if selection.Settings {
+		current.APIKEY = secret(bundleAPIKeyPath, imported.APIKEY, current.APIKEY, envPlaceholder("APIKEY"))
+		// 去除了凭据的同一代理保留本地带凭据的地址
+		if _, ok := secrets[bundleProxyPath]; ok || !keepsProxyCredentials(imported.PROXY_URL, current.PROXY_URL) {
+			current.PROXY_URL = secret(bundleProxyPath, imported.PROXY_URL, current.PROXY_URL, envPlaceholder("PROXY_URL"))
+		}
+		current.API_TIMEOUT_MS = imported.API_TIMEOUT_MS
+		current.LOG = imported.LOG
+	}
+
+	selectedProviders := make(map[string]bool)
+	for _, name := range selection.Providers {
+		selectedProviders[name] = true
+	}
+	providers := make([]Provider, len(current.Providers))
+	copy(providers, current.Providers)
+	for _, provider := range imported.Providers {
+		if !selectedProviders[provider.Name] {
+			continue
+		}
+		index := -1
+		for i := range providers {
+			if providers[i].Name == provider.Name {
+				index = i
+				break
+			}
+		}
+		local := ""
+		if index >= 0 {
+			local = providers[index].APIKey
+		}
+		provider.APIKey = secret(providerSecretPath(provider.Name), provider.APIKey, local, envPlaceholder(provider.Name+"_API_KEY"))
+		if index >= 0 {
+			providers[index] = provider
+		} else {
+			providers = append(providers, provider)
+		}
+	}
+	current.Providers = providers
+
+	if len(selection.Slots) > 0 {
+		fallback := make(map[string][]string, len(current.Fallback))
+		for slot, chain := range current.Fallback {
+			fallback[slot] = chain
+		}
+		for _, slot := range selection.Slots {
+			current.Router.setSlot(slot, imported.Router.slot(slot))
+			if chain := imported.Fallback[slot]; len(chain) > 0 {
+				fallback[slot] = chain
+			} else {
+				delete(fallback, slot)
+			}
+			if slot == "longContext" && imported.Router.LongContextThreshold > 0 {
+				current.Router.LongContextThreshold = imported.Router.LongContextThreshold
+			}
+		}
+		if len(fallback) == 0 {
+			fallback = nil
+		}
+		current.Fallback = fallback
+	}
+
+	if selection.Plugins {
+		plugins := append([]TransformerPlugin{}, current.Transformers...)
+		for _, plugin := range imported.Transformers {
+			if target, ok := pluginTargets[plugin.Path]; ok {
+				plugin.Path = target
+			}
+			registered := false
+			for _, existing := range plugins {
+				if resolveScriptPath(existing.Path) == resolveScriptPath(plugin.Path) {
+					registered = true
+					break
+				}
+			}
+			if !registered {
+				plugins = append(plugins, plugin)
+			}
+		}
+		current.Transformers = plugins
+	}
+
+	if selection.CustomRouter && routerTarget != "" {
+		current.CUSTOM_ROUTER_PATH = routerTarget
+	}
+
+	return current
+}
+
+// fileTargets maps the config paths of the bundle scripts to where they are
+// written on import
+func fileTargets(b *bundle, plugins []ImportFile, router *ImportFile) (map[string]string, string) {
+	pluginTargets := make(map[string]string)
+	for i, file := range plugins {
+		pluginTargets[b.manifest.Plugins[i].Path] = file.Target
+	}
+	routerTarget := ""
+	if router != nil {
+		routerTarget = router.Target
+	}
+	return pluginTargets, routerTarget
+}
+
+// readCurrentConfig decodes config.json for an import, an empty config when
+// there is none. The caller must hold configMu.
+func (a *App) readCurrentConfig() (Config, error) {
+	var config Config
+	data, err := os.ReadFile(a.GetConfigPath())
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config: %v", err)
+	}
+	return config, nil
+}
+
+// ImportConfig reads a bundle and previews importing it: each provider and
+// Router slot compared with the current config, the files it would write and
+// the diff of importing everything. Nothing is changed.
+func (a *App) ImportConfig(bundlePath string) (ImportPreview, error) {
+	preview := ImportPreview{
+		Path:      bundlePath,
+		Providers: []ImportItem{},
+		Slots:     []ImportItem{},
+		Changes:   []ConfigChange{},
+		Issues:    []ValidationIssue{},
+	}
+
+	b, err := readBundle(bundlePath)
+	if err != nil {
+		return preview, err
+	}
+	preview.CreatedAt = b.manifest.CreatedAt
+	preview.Secrets = b.manifest.Secrets
+	_, preview.Encrypted = b.entries[bundleSecretsEntry]
+	preview.Plugins, preview.CustomRouter, preview.Presets = a.importFiles(b)
+
+	a.configMu.Lock()
+	current, err := a.readCurrentConfig()
+	a.configMu.Unlock()
+	if err != nil {
+		return preview, err
+	}
+
+	all := ImportSelection{Settings: true, Plugins: true, CustomRouter: true, Presets: true}
+	for _, provider := range b.config.Providers {
+		all.Providers = append(all.Providers, provider.Name)
+	}
+	for _, slot := range b.config.Router.slotNames() {
+		if b.config.Router.slot(slot) != "" || len(b.config.Fallback[slot]) > 0 {
+			all.Slots = append(all.Slots, slot)
+		}
+	}
+	pluginTargets, routerTarget := fileTargets(b, preview.Plugins, preview.CustomRouter)
+	merged := mergeBundle(current, b, all, nil, pluginTargets, routerTarget)
+
+	existing := make(map[string]Provider)
+	for _, provider := range current.Providers {
+		existing[provider.Name] = provider
+	}
+	for _, provider := range merged.Providers {
+		if !contains(all.Providers, provider.Name) {
+			continue
+		}
+		item := ImportItem{Name: provider.Name, Status: ImportNew, After: provider.APIBaseURL}
+		if old, ok := existing[provider.Name]; ok {
+			item.Before = old.APIBaseURL
+			item.Status = ImportUnchanged
+			before, _ := json.Marshal(old)
+			after, _ := json.Marshal(provider)
+			if !bytes.Equal(before, after) {
+				item.Status = ImportChanged
+			}
+		}
+		preview.Providers = append(preview.Providers, item)
+	}
+
+	for _, slot := range all.Slots {
+		item := ImportItem{Name: slot, Before: current.Router.slot(slot), After: b.config.Router.slot(slot)}
+		switch {
+		case item.Before == "" && len(current.Fallback[slot]) == 0:
+			item.Status = ImportNew
+		case item.Before != item.After || strings.Join(current.Fallback[slot], ",") != strings.Join(b.config.Fallback[slot], ","):
+			item.Status = ImportChanged
+		default:
+			item.Status = ImportUnchanged
+		}
+		preview.Slots = append(preview.Slots, item)
+	}
+
+	preview.Changes = diffConfigs(current, merged)
+	for _, issue := range a.ValidateConfig(merged) {
+		if issue.Severity == SeverityError {
+			preview.Issues = append(preview.Issues, issue)
+		}
+	}
+
+	return preview, nil
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// stagedFile is an imported file waiting to be written
+type stagedFile struct {
+	target string
+	data   []byte
+	perm   os.FileMode
+}
+
+// writeStagedFiles writes files and returns a function that puts back what
+// they replaced. If a write fails, the files already written are put back
+// before returning.
+func (a *App) writeStagedFiles(files []stagedFile) (func(), error) {
+	type replaced struct {
+		file     stagedFile
+		previous []byte
+		existed  bool
+	}
+	var written []replaced
+	undo := func() {
+		for i := len(written) - 1; i >= 0; i-- {
+			item := written[i]
+			if !item.existed {
+				os.Remove(item.file.target)
+			} else if err := writeFileAtomic(item.file.target, item.previous, item.file.perm); err != nil && a.logger != nil {
+				a.logger.Printf("ERROR: Failed to restore %s: %v", item.file.target, err)
+			}
+		}
+	}
+
+	for _, file := range files {
+		previous, err := os.ReadFile(file.target)
+		if err != nil && !os.IsNotExist(err) {
+			undo()
+			return nil, fmt.Errorf("failed to read %s: %v", file.target, err)
+		}
+		existed := err == nil
+		if err := os.MkdirAll(filepath.Dir(file.target), 0755); err != nil {
+			undo()
+			return nil, fmt.Errorf("failed to create directory: %v", err)
+		}
+		if err := writeFileAtomic(file.target, file.data, file.perm); err != nil {
+			undo()
+			return nil, err
+		}
+		written = append(written, replaced{file: file, previous: previous, existed: existed})
+	}
+	return undo, nil
+}
+
+// ApplyImport imports the selected parts of a bundle. The merged config is
+// validated first; selected scripts and presets are then written and the
+// config saved after backing up the current one. If saving fails, the
+// replaced scripts and presets are put back. Providers are matched by name;
+// when the bundle has no secret for a field the local value is kept.
+func (a *App) ApplyImport(bundlePath string, selection ImportSelection) error {
+	b, err := readBundle(bundlePath)
+	if err != nil {
+		return err
+	}
+
+	secrets := map[string]string{}
+	if sealed, ok := b.entries[bundleSecretsEntry]; ok && selection.Passphrase != "" {
+		v, err := openVault(sealed, selection.Passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt bundle secrets: %v", err)
+		}
+		for secretPath, entry := range v.entries {
+			secrets[secretPath] = entry.Value
+		}
+	}
+
+	plugins, router, presets := a.importFiles(b)
+	if !selection.Plugins {
+		plugins = nil
+	}
+	if !selection.CustomRouter {
+		router = nil
+	}
+	if !selection.Presets {
+		presets = nil
+	}
+
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	current, err := a.readCurrentConfig()
+	if err != nil {
+		return err
+	}
+	pluginTargets, routerTarget := fileTargets(b, plugins, router)
+	config := mergeBundle(current, b, selection, secrets, pluginTargets, routerTarget)
+
+	if issues := a.ValidateConfig(config); hasValidationErrors(issues) {
+		return fmt.Errorf("imported config is invalid: %s", summarizeIssues(issues))
+	}
+
+	// 先准备好所有文件内容，任何一步失败都不改动磁盘
+	var staged []stagedFile
+	for _, file := range plugins {
+		staged = append(staged, stagedFile{target: file.Target, data: b.entries[file.Entry], perm: 0644})
+	}
+	if router != nil {
+		staged = append(staged, stagedFile{target: router.Target, data: b.entries[router.Entry], perm: 0644})
+	}
+	for _, file := range presets {
+		data := b.entries[file.Entry]
+		var preset ProviderPreset
+		if err := json.Unmarshal(data, &preset); err != nil {
+			return fmt.Errorf("bundle preset %s is not valid: %v", file.Entry, err)
+		}
+		if decrypted, ok := secrets[presetSecretPath(preset.ID)]; ok {
+			preset.APIKey = decrypted
+			if data, err = json.MarshalIndent(preset, "", "  "); err != nil {
+				return err
+			}
+		}
+		// 模板中可能含有密钥，仅允许当前用户读取
+		staged = append(staged, stagedFile{target: file.Target, data: data, perm: 0600})
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	configPath := a.GetConfigPath()
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+
+	// 脚本先于配置写入，配置引用的文件在保存配置时已存在；保存失败时还原
+	undoFiles, err := a.writeStagedFiles(staged)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write imported files: %v", err)
+		}
+		return err
+	}
+	if _, err := a.backupConfig(); err != nil {
+		undoFiles()
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to back up config before import: %v", err)
+		}
+		return err
+	}
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
+		undoFiles()
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write imported config: %v", err)
+		}
+		return err
+	}
+	// 不更新 loadedConfig：界面仍持有旧配置，由文件监视通知其重新加载
+	if _, err := a.recordRevision(data, "imported bundle "+filepath.Base(bundlePath)); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record config revision: %v", err)
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Imported config bundle %s (%d providers, %d slots)", bundlePath, len(selection.Providers), len(selection.Slots))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// envRefPattern matches ${NAME} and $NAME references
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// EnvReference describes an environment variable referenced by a config value.
+// The variable's value is never included.
+type EnvReference struct {
+	Path     string `json:"path"`
+	Variable string `json:"variable"`
+	Resolved bool   `json:"resolved"`
+}
+
+// envRefNames returns the variable names referenced by value
+func envRefNames(value string) []string {
+	var names []string
+	for _, match := range envRefPattern.FindAllStringSubmatch(value, -1) {
+		if match[1] != "" {
+			names = append(names, match[1])
+		} else {
+			names = append(names, match[2])
+		}
+	}
+	return names
+}
+
+// expandEnvRefs replaces variable references in value, returning the names of
+// variables that are not set. Unset references are left as written.
+func expandEnvRefs(value string) (string, []string) {
+	var missing []string
+	expanded := envRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		match := envRefPattern.FindStringSubmatch(ref)
+		name := match[1]
+		if name == "" {
+			name = match[2]
+		}
+		if resolved, ok := os.LookupEnv(name); ok {
+			return resolved
+		}
+		missing = append(missing, name)
+		return ref
+	})
+	return expanded, missing
+}
+
+// configValue is a string config value together with its field path
+type configValue struct {
+	Path  string
+	Value string
+}
+
+// envFields lists the config values that may reference environment variables
+func envFields(config Config) []configValue {
+	fields := []configValue{
+		{Path: "APIKEY", Value: config.APIKEY},
+		{Path: "PROXY_URL", Value: config.PROXY_URL},
+	}
+	for i, provider := range config.Providers {
+		fields = append(fields,
+			configValue{Path: fmt.Sprintf("Providers[%d].api_base_url", i), Value: provider.APIBaseURL},
+			configValue{Path: fmt.Sprintf("Providers[%d].api_key", i), Value: provider.APIKey},
+		)
+	}
+	return fields
+}
+
+// findEnvReferences lists every environment variable reference in config
+func findEnvReferences(config Config) []EnvReference {
+	references := []EnvReference{}
+	for _, field := range envFields(config) {
+		for _, name := range envRefNames(field.Value) {
+			_, resolved := os.LookupEnv(name)
+			references = append(references, EnvReference{Path: field.Path, Variable: name, Resolved: resolved})
+		}
+	}
+	return references
+}
+
+// expandConfigEnv returns a copy of config with environment variable
+// references expanded. It is used whenever a value is needed for real work;
+// the reference text itself is what gets saved.
+func expandConfigEnv(config Config) Config {
+	config.APIKEY, _ = expandEnvRefs(config.APIKEY)
+	config.PROXY_URL, _ = expandEnvRefs(config.PROXY_URL)
+
+	providers := make([]Provider, len(config.Providers))
+	copy(providers, config.Providers)
+	for i := range providers {
+		providers[i].APIKey, _ = expandEnvRefs(providers[i].APIKey)
+		providers[i].APIBaseURL, _ = expandEnvRefs(providers[i].APIBaseURL)
+	}
+	config.Providers = providers
+
+	return config
+}
+
+// GetEnvReferences lists the environment variable references in config and
+// whether each one is set, without revealing any values
+func (a *App) GetEnvReferences(config Config) []EnvReference {
+	return findEnvReferences(config)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// rawField is a single member of a JSON object, kept in its raw form
+type rawField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// objectFields remembers the key order of a decoded JSON object together with
+// the members this manager does not model, so they can be written back untouched
+type objectFields struct {
+	order []string
+	extra []rawField
+}
+
+// decodeObject splits a JSON object into its members, preserving their order
+func decodeObject(data []byte) ([]rawField, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected JSON object")
+	}
+
+	var fields []rawField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected object key, got %v", token)
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+
+		// 重复的键以最后一次出现为准，与 encoding/json 的行为保持一致
+		replaced := false
+		for i := range fields {
+			if fields[i].Key == key {
+				fields[i].Value = value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fields = append(fields, rawField{Key: key, Value: value})
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// encodeObject writes members as a JSON object in the given order
+func encodeObject(fields []rawField) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(field.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// capture records the key order of fields and keeps every member whose key is
+// not reported as known
+func (o *objectFields) capture(fields []rawField, known func(key string) bool) {
+	o.order = o.order[:0]
+	o.extra = nil
+	for _, field := range fields {
+		o.order = append(o.order, field.Key)
+		if !known(field.Key) {
+			o.extra = append(o.extra, field)
+		}
+	}
+}
+
+// arrange merges the modelled members with the preserved ones. Keys seen when
+// the object was decoded keep their position; anything new is appended in the
+// order it was given.
+func (o *objectFields) arrange(known []rawField) []rawField {
+	pending := make(map[string]rawField, len(known)+len(o.extra))
+	var fallback []string
+	for _, field := range known {
+		pending[field.Key] = field
+		fallback = append(fallback, field.Key)
+	}
+	for _, field := range o.extra {
+		if _, ok := pending[field.Key]; !ok {
+			pending[field.Key] = field
+			fallback = append(fallback, field.Key)
+		}
+	}
+
+	result := make([]rawField, 0, len(pending))
+	for _, key := range append(append([]string{}, o.order...), fallback...) {
+		if field, ok := pending[key]; ok {
+			result = append(result, field)
+			delete(pending, key)
+		}
+	}
+	return result
+}
+
+// inherit copies preserved members from a previously decoded object for keys
+// this object does not carry itself, and adopts its key order
+func (o *objectFields) inherit(previous objectFields, has func(key string) bool) {
+	for _, field := range previous.extra {
+		if has(field.Key) {
+			continue
+		}
+		duplicate := false
+		for _, existing := range o.extra {
+			if existing.Key == field.Key {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			o.extra = append(o.extra, field)
+		}
+	}
+
+	order := append([]string{}, previous.order...)
+	for _, key := range o.order {
+		found := false
+		for _, existing := range order {
+			if existing == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			order = append(order, key)
+		}
+	}
+	o.order = order
+}
+
+// hasKey reports whether the given key was present when the object was decoded
+func (o *objectFields) hasKey(key string) bool {
+	for _, existing := range o.order {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,712 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ConfigSchemaVersion is the version of the typed config schema below. It is
+// bumped whenever the meaning or shape of a modelled field changes.
+const ConfigSchemaVersion = 1
+
+const (
+	defaultPort         = 3456
+	defaultAPITimeoutMS = 600000
+	// defaultLongContextThreshold is the token count CCR uses when
+	// longContextThreshold is not set
+	defaultLongContextThreshold = 60000
+)
+
+// Severity levels reported by ValidateConfig
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue describes a single problem found in a configuration
+type ValidationIssue struct {
+	Path     string `json:"path"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// unsetConfigValues are the encoded zero values of top-level keys that are
+// always written by encoding/json. They are left out unless the file had them.
+var unsetConfigValues = map[string]string{
+	"LOG":    "false",
+	"Router": "{}",
+}
+
+// configKeys lists the top-level keys modelled by Config
+var configKeys = map[string]bool{
+	"APIKEY":             true,
+	"PROXY_URL":          true,
+	"HOST":               true,
+	"PORT":               true,
+	"API_TIMEOUT_MS":     true,
+	"LOG":                true,
+	"NPM_GLOBAL_PREFIX":  true,
+	"CUSTOM_ROUTER_PATH": true,
+	"Providers":          true,
+	"Router":             true,
+	"fallback":           true,
+	"transformers":       true,
+}
+
+// providerKeys lists the provider keys modelled by Provider
+var providerKeys = map[string]bool{
+	"name":         true,
+	"api_base_url": true,
+	"api_key":      true,
+	"models":       true,
+	"transformer":  true,
+	"savedName":    true,
+}
+
+// routerKeys lists the router keys modelled by Router
+var routerKeys = map[string]bool{
+	"default":              true,
+	"background":           true,
+	"think":                true,
+	"longContext":          true,
+	"longContextThreshold": true,
+	"webSearch":            true,
+	"image":                true,
+}
+
+// schemaDecoder decodes loosely typed config values. Lossless conversions
+// (such as "3456" for a port) are accepted, everything else is recorded as an
+// issue instead of being replaced by a default.
+type schemaDecoder struct {
+	issues []ValidationIssue
+}
+
+// fail records a value that could not be decoded
+func (d *schemaDecoder) fail(path string, format string, args ...interface{}) {
+	d.issues = append(d.issues, ValidationIssue{
+		Path:     path,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// err turns the recorded issues into a single error, if there are any
+func (d *schemaDecoder) err() error {
+	if len(d.issues) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", summarizeIssues(d.issues))
+}
+
+// decodeValue decodes raw into a generic value, keeping numbers exact
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// jsonKind names the JSON type of a decoded value for error messages
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// joinPath appends a key to a field path
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func (d *schemaDecoder) decodeString(path string, raw json.RawMessage) string {
+	value, err := decodeValue(raw)
+	if err != nil {
+		d.fail(path, "%v", err)
+		return ""
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		d.fail(path, "expected a string, got %s", jsonKind(v))
+		return ""
+	}
+}
+
+func (d *schemaDecoder) decodeInt(path string, raw json.RawMessage) int {
+	value, err := decodeValue(raw)
+	if err != nil {
+		d.fail(path, "%v", err)
+		return 0
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case json.Number:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil && f == float64(int(f)) {
+			return int(f)
+		}
+		d.fail(path, "%s is not a whole number", v.String())
+		return 0
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+		d.fail(path, "%q is not a valid integer", v)
+		return 0
+	default:
+		d.fail(path, "expected an integer, got %s", jsonKind(v))
+		return 0
+	}
+}
+
+func (d *schemaDecoder) decodeBool(path string, raw json.RawMessage) bool {
+	value, err := decodeValue(raw)
+	if err != nil {
+		d.fail(path, "%v", err)
+		return false
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+		d.fail(path, "%q is not a valid boolean", v)
+		return false
+	case json.Number:
+		switch v.String() {
+		case "0":
+			return false
+		case "1":
+			return true
+		}
+		d.fail(path, "%s is not a valid boolean", v.String())
+		return false
+	default:
+		d.fail(path, "expected a boolean, got %s", jsonKind(v))
+		return false
+	}
+}
+
+func (d *schemaDecoder) decodeStrings(path string, raw json.RawMessage) []string {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		d.fail(path, "expected a list of strings")
+		return nil
+	}
+
+	result := make([]string, 0, len(items))
+	for i, item := range items {
+		result = append(result, d.decodeString(fmt.Sprintf("%s[%d]", path, i), item))
+	}
+	return result
+}
+
+// decodeObjectAt splits raw into members, recording an issue if it is not an object
+func (d *schemaDecoder) decodeObjectAt(path string, raw json.RawMessage) ([]rawField, bool) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, false
+	}
+	fields, err := decodeObject(raw)
+	if err != nil {
+		d.fail(path, "expected an object: %v", err)
+		return nil, false
+	}
+	return fields, true
+}
+
+func (d *schemaDecoder) decodeProvider(path string, raw json.RawMessage) Provider {
+	var provider Provider
+	fields, ok := d.decodeObjectAt(path, raw)
+	if !ok {
+		return provider
+	}
+
+	// 无法解析的转换器配置原样保留，避免保存时丢失
+	keepTransformer := false
+
+	for _, field := range fields {
+		fieldPath := joinPath(path, field.Key)
+		switch field.Key {
+		case "name":
+			provider.Name = d.decodeString(fieldPath, field.Value)
+		case "api_base_url":
+			provider.APIBaseURL = d.decodeString(fieldPath, field.Value)
+		case "savedName":
+			provider.SavedName = d.decodeString(fieldPath, field.Value)
+		case "api_key":
+			provider.APIKey = d.decodeString(fieldPath, field.Value)
+		case "models":
+			provider.Models = d.decodeStrings(fieldPath, field.Value)
+		case "transformer":
+			if bytes.Equal(bytes.TrimSpace(field.Value), []byte("null")) {
+				continue
+			}
+			var transformer ProviderTransformer
+			if err := json.Unmarshal(field.Value, &transformer); err != nil {
+				d.fail(fieldPath, "%v", err)
+				keepTransformer = true
+				continue
+			}
+			provider.Transformer = &transformer
+		}
+	}
+
+	provider.fields.capture(fields, func(key string) bool {
+		return providerKeys[key] && !(key == "transformer" && keepTransformer)
+	})
+	return provider
+}
+
+func (d *schemaDecoder) decodeProviders(path string, raw json.RawMessage) []Provider {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		d.fail(path, "expected a list of providers")
+		return nil
+	}
+
+	providers := make([]Provider, 0, len(items))
+	for i, item := range items {
+		providers = append(providers, d.decodeProvider(fmt.Sprintf("%s[%d]", path, i), item))
+	}
+	return providers
+}
+
+func (d *schemaDecoder) decodeRouter(path string, raw json.RawMessage) Router {
+	var router Router
+	fields, ok := d.decodeObjectAt(path, raw)
+	if !ok {
+		return router
+	}
+
+	for _, field := range fields {
+		fieldPath := joinPath(path, field.Key)
+		switch field.Key {
+		case "default":
+			router.Default = d.decodeString(fieldPath, field.Value)
+		case "background":
+			router.Background = d.decodeString(fieldPath, field.Value)
+		case "think":
+			router.Think = d.decodeString(fieldPath, field.Value)
+		case "longContext":
+			router.LongContext = d.decodeString(fieldPath, field.Value)
+		case "longContextThreshold":
+			router.LongContextThreshold = d.decodeInt(fieldPath, field.Value)
+		case "webSearch":
+			router.WebSearch = d.decodeString(fieldPath, field.Value)
+		case "image":
+			router.Image = d.decodeString(fieldPath, field.Value)
+		default:
+			// 其他字符串值视为新增的路由槽位，其余原样保留
+			var reference string
+			if json.Unmarshal(field.Value, &reference) == nil {
+				if router.Slots == nil {
+					router.Slots = make(map[string]string)
+				}
+				router.Slots[field.Key] = reference
+			}
+		}
+	}
+
+	router.fields.capture(fields, func(key string) bool {
+		_, isSlot := router.Slots[key]
+		return routerKeys[key] || isSlot
+	})
+	return router
+}
+
+func (d *schemaDecoder) decodeFallback(path string, raw json.RawMessage) map[string][]string {
+	fields, ok := d.decodeObjectAt(path, raw)
+	if !ok || len(fields) == 0 {
+		return nil
+	}
+
+	fallback := make(map[string][]string, len(fields))
+	for _, field := range fields {
+		if bytes.Equal(bytes.TrimSpace(field.Value), []byte("null")) {
+			continue
+		}
+		fallback[field.Key] = d.decodeStrings(joinPath(path, field.Key), field.Value)
+	}
+	return fallback
+}
+
+// UnmarshalJSON decodes the modelled fields and keeps every other top-level
+// key. Values that do not fit the schema are kept as issues on the config
+// rather than failing the whole file.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	fields, err := decodeObject(data)
+	if err != nil {
+		return err
+	}
+
+	d := &schemaDecoder{}
+	*c = Config{}
+	for _, field := range fields {
+		switch field.Key {
+		case "APIKEY":
+			c.APIKEY = d.decodeString(field.Key, field.Value)
+		case "PROXY_URL":
+			c.PROXY_URL = d.decodeString(field.Key, field.Value)
+		case "HOST":
+			c.HOST = d.decodeString(field.Key, field.Value)
+		case "PORT":
+			c.PORT = d.decodeInt(field.Key, field.Value)
+		case "API_TIMEOUT_MS":
+			c.API_TIMEOUT_MS = d.decodeInt(field.Key, field.Value)
+		case "LOG":
+			c.LOG = d.decodeBool(field.Key, field.Value)
+		case "NPM_GLOBAL_PREFIX":
+			c.NPM_GLOBAL_PREFIX = d.decodeString(field.Key, field.Value)
+		case "CUSTOM_ROUTER_PATH":
+			c.CUSTOM_ROUTER_PATH = d.decodeString(field.Key, field.Value)
+		case "Providers":
+			c.Providers = d.decodeProviders(field.Key, field.Value)
+		case "Router":
+			c.Router = d.decodeRouter(field.Key, field.Value)
+		case "fallback":
+			c.Fallback = d.decodeFallback(field.Key, field.Value)
+		case "transformers":
+			c.Transformers = d.decodePlugins(field.Key, field.Value)
+		}
+	}
+
+	c.fields.capture(fields, func(key string) bool { return configKeys[key] })
+	c.issues = d.issues
+	return nil
+}
+
+// configAlias has the same fields as Config without its JSON methods
+type configAlias Config
+
+// MarshalJSON encodes the modelled fields together with the preserved keys,
+// following the key order the config was loaded with
+func (c Config) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(configAlias(c))
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 文件中原本没有的空值不写出，避免每次保存都凭空增加键
+	present := known[:0]
+	for _, field := range known {
+		if zero, ok := unsetConfigValues[field.Key]; ok && string(field.Value) == zero && !c.fields.hasKey(field.Key) {
+			continue
+		}
+		present = append(present, field)
+	}
+
+	return encodeObject(c.fields.arrange(present)), nil
+}
+
+// UnmarshalJSON decodes a single provider, rejecting values that do not fit the schema
+func (p *Provider) UnmarshalJSON(data []byte) error {
+	d := &schemaDecoder{}
+	*p = d.decodeProvider("", data)
+	return d.err()
+}
+
+// providerAlias has the same fields as Provider without its JSON methods
+type providerAlias Provider
+
+// MarshalJSON encodes the provider together with its preserved keys
+func (p Provider) MarshalJSON() ([]byte, error) {
+	alias := providerAlias(p)
+	if alias.Models == nil {
+		// CCR 要求 models 始终是数组
+		alias.Models = []string{}
+	}
+
+	data, err := json.Marshal(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeObject(p.fields.arrange(known)), nil
+}
+
+// UnmarshalJSON decodes the router, rejecting values that do not fit the schema
+func (r *Router) UnmarshalJSON(data []byte) error {
+	d := &schemaDecoder{}
+	*r = d.decodeRouter("", data)
+	return d.err()
+}
+
+// routerAlias has the same fields as Router without its JSON methods
+type routerAlias Router
+
+// MarshalJSON encodes the router together with its additional slots and
+// preserved keys
+func (r Router) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(routerAlias(r))
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range r.extraSlotNames() {
+		if r.Slots[name] == "" {
+			continue
+		}
+		value, err := json.Marshal(r.Slots[name])
+		if err != nil {
+			return nil, err
+		}
+		known = append(known, rawField{Key: name, Value: value})
+	}
+
+	return encodeObject(r.fields.arrange(known)), nil
+}
+
+// inheritUnknownFields carries keys that are unknown to this manager over from
+// previous when c does not set them, so saving a config edited in the UI does
+// not drop keys the UI never saw. Providers are matched by name, plugins by
+// path.
+func (c *Config) inheritUnknownFields(previous Config) {
+	c.fields.inherit(previous.fields, c.fields.hasKey)
+	c.Router.fields.inherit(previous.Router.fields, c.Router.fields.hasKey)
+
+	for i := range c.Transformers {
+		for _, old := range previous.Transformers {
+			if old.Path == c.Transformers[i].Path {
+				c.Transformers[i].fields.inherit(old.fields, c.Transformers[i].fields.hasKey)
+				break
+			}
+		}
+	}
+
+	for i := range c.Providers {
+		for _, old := range previous.Providers {
+			if old.Name == c.Providers[i].Name {
+				c.Providers[i].fields.inherit(old.fields, c.Providers[i].fields.hasKey)
+				break
+			}
+		}
+	}
+}
+
+// summarizeIssues joins issues into a single line for error messages
+func summarizeIssues(issues []ValidationIssue) string {
+	parts := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		parts = append(parts, fmt.Sprintf("%s: %s", issue.Path, issue.Message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// hasValidationErrors reports whether any issue has error severity
+func hasValidationErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// validateURL checks that value is an absolute URL using one of the given
+// schemes. Environment variable references are expanded first; values whose
+// references are not set are left to the unresolved reference check. Messages
+// quote the value as written, so expanded secrets are never reported.
+func validateURL(value string, schemes ...string) string {
+	expanded, missing := expandEnvRefs(value)
+	if len(missing) > 0 {
+		return ""
+	}
+
+	parsed, err := url.Parse(expanded)
+	if err != nil {
+		// url.Error 的消息包含完整的 URL，只保留原因
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Sprintf("%q is not a valid URL: %v", value, err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Sprintf("%q is not an absolute URL", value)
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(parsed.Scheme, scheme) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("unsupported URL scheme %q, expected one of %s", parsed.Scheme, strings.Join(schemes, ", "))
+}
+
+// validateConfig checks the semantic rules of the schema
+func validateConfig(config Config) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(path, severity, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Path: path, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if config.PORT != 0 && (config.PORT < 1 || config.PORT > 65535) {
+		add("PORT", SeverityError, "port %d is out of range, expected 1-65535", config.PORT)
+	}
+
+	if config.API_TIMEOUT_MS < 0 {
+		add("API_TIMEOUT_MS", SeverityError, "timeout must not be negative")
+	}
+
+	if config.PROXY_URL != "" {
+		if msg := validateURL(config.PROXY_URL, "http", "https", "socks4", "socks5", "socks5h"); msg != "" {
+			add("PROXY_URL", SeverityError, "%s", msg)
+		}
+	}
+
+	// CCR 在未设置 APIKEY 时会强制监听 127.0.0.1
+	if config.APIKEY == "" && config.HOST != "" && config.HOST != "127.0.0.1" && config.HOST != "localhost" {
+		add("HOST", SeverityWarning, "HOST is forced to 127.0.0.1 when APIKEY is not set")
+	}
+
+	knownTransformers := knownTransformerNames(config)
+	seen := make(map[string]bool)
+	for i, provider := range config.Providers {
+		path := fmt.Sprintf("Providers[%d]", i)
+
+		name := strings.TrimSpace(provider.Name)
+		switch {
+		case name == "":
+			add(path+".name", SeverityError, "provider name is required")
+		case strings.Contains(name, ","):
+			add(path+".name", SeverityError, "provider name must not contain a comma")
+		case seen[name]:
+			add(path+".name", SeverityError, "duplicate provider name %q", name)
+		}
+		seen[name] = true
+
+		if provider.APIBaseURL == "" {
+			add(path+".api_base_url", SeverityError, "api_base_url is required")
+		} else if msg := validateURL(provider.APIBaseURL, "http", "https"); msg != "" {
+			add(path+".api_base_url", SeverityError, "%s", msg)
+		}
+
+		if len(provider.Models) == 0 {
+			add(path+".models", SeverityError, "at least one model is required")
+		}
+		models := make(map[string]bool)
+		for j, model := range provider.Models {
+			modelPath := fmt.Sprintf("%s.models[%d]", path, j)
+			model = strings.TrimSpace(model)
+			if model == "" {
+				add(modelPath, SeverityError, "model name must not be empty")
+				continue
+			}
+			if models[model] {
+				add(modelPath, SeverityWarning, "duplicate model %q", model)
+			}
+			models[model] = true
+		}
+
+		validateTransformer(path+".transformer", provider, knownTransformers, add)
+	}
+
+	validatePlugins(config, add)
+	validateCustomRouter(config, add)
+
+	for _, reference := range findEnvReferences(config) {
+		if !reference.Resolved {
+			add(reference.Path, SeverityWarning, "environment variable %s is not set", reference.Variable)
+		}
+	}
+
+	if config.Router.LongContextThreshold < 0 {
+		add("Router.longContextThreshold", SeverityError, "threshold must not be negative")
+	}
+
+	issues = append(issues, checkRouterReferences(config)...)
+
+	return issues
+}
+
+// ValidateConfig checks a configuration against the schema and returns every
+// problem found, so the UI can highlight them before saving
+func (a *App) ValidateConfig(config Config) []ValidationIssue {
+	issues := append([]ValidationIssue{}, config.issues...)
+	issues = append(issues, validateConfig(config)...)
+	issues = append(issues, a.checkSecretReferences(config)...)
+	return issues
+}
+
+// ValidateConfigFile validates the config file currently on disk, including
+// values that could not be decoded into the schema
+func (a *App) ValidateConfigFile() ([]ValidationIssue, error) {
+	configPath := a.GetConfigPath()
+	if configPath == "" {
+		return nil, fmt.Errorf("could not determine config path")
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return []ValidationIssue{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON config: %v", err)
+	}
+
+	return a.ValidateConfig(config), nil
+}
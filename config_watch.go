@@ -0,0 +1,401 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// EventConfigChanged is emitted when config.json changes on disk and no
+// longer matches what the UI loaded
+const EventConfigChanged = "config:changed"
+
+// configWatchDebounce groups the bursts of events editors produce on save
+const configWatchDebounce = 300 * time.Millisecond
+
+// configSnapshot identifies the content of config.json at a point in time
+type configSnapshot struct {
+	exists bool
+	hash   string
+	data   []byte
+}
+
+// ConfigFileStatus reports whether config.json changed since it was loaded
+type ConfigFileStatus struct {
+	Path       string `json:"path"`
+	Exists     bool   `json:"exists"`
+	ModTime    string `json:"modTime,omitempty"`
+	LoadedHash string `json:"loadedHash,omitempty"`
+	DiskHash   string `json:"diskHash,omitempty"`
+	Diverged   bool   `json:"diverged"`
+}
+
+// configWatcher watches the config directory for changes to config.json
+type configWatcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+
+	mu           sync.Mutex
+	timer        *time.Timer
+	notifiedHash string
+}
+
+// newConfigSnapshot builds a snapshot from raw file content, nil meaning the
+// file does not exist
+func newConfigSnapshot(data []byte) *configSnapshot {
+	if data == nil {
+		return &configSnapshot{}
+	}
+	sum := sha256.Sum256(data)
+	return &configSnapshot{exists: true, hash: hex.EncodeToString(sum[:]), data: data}
+}
+
+// readConfigSnapshot captures the current content of config.json
+func (a *App) readConfigSnapshot() (*configSnapshot, error) {
+	data, err := os.ReadFile(a.GetConfigPath())
+	if os.IsNotExist(err) {
+		return newConfigSnapshot(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newConfigSnapshot(data), nil
+}
+
+// GetConfigFileStatus reports whether config.json changed on disk since the
+// last LoadConfig
+func (a *App) GetConfigFileStatus() (ConfigFileStatus, error) {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	status := ConfigFileStatus{Path: a.GetConfigPath()}
+
+	disk, err := a.readConfigSnapshot()
+	if err != nil {
+		return status, err
+	}
+	status.Exists = disk.exists
+	status.DiskHash = disk.hash
+	if info, err := os.Stat(status.Path); err == nil {
+		status.ModTime = info.ModTime().Format(time.RFC3339)
+	}
+
+	if a.loadedConfig != nil {
+		status.LoadedHash = a.loadedConfig.hash
+		status.Diverged = a.loadedConfig.hash != disk.hash
+	}
+
+	return status, nil
+}
+
+// reconcileExternalChanges checks whether config.json changed on disk since it
+// was loaded. If it did, the changes made in the UI are merged with the ones
+// made on disk; conflicting edits to the same value are reported as an error.
+// The caller must hold configMu.
+func (a *App) reconcileExternalChanges(config Config) (Config, error) {
+	if a.loadedConfig == nil {
+		return config, nil
+	}
+
+	disk, err := a.readConfigSnapshot()
+	if err != nil {
+		return config, fmt.Errorf("failed to read config for change detection: %v", err)
+	}
+	if disk.hash == a.loadedConfig.hash {
+		return config, nil
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("WARNING: Config file changed on disk since it was loaded, merging changes")
+	}
+
+	base, err := a.canonicalConfig(a.loadedConfig.data)
+	if err != nil {
+		return config, fmt.Errorf("failed to parse loaded config: %v", err)
+	}
+	theirs, err := a.canonicalConfig(disk.data)
+	if err != nil {
+		return config, fmt.Errorf("config file was changed on disk and can no longer be parsed: %v", err)
+	}
+
+	var baseConfig Config
+	if a.loadedConfig.data != nil {
+		json.Unmarshal(a.loadedConfig.data, &baseConfig)
+	}
+	config.inheritUnknownFields(baseConfig)
+	oursData, err := json.Marshal(a.applyConfigDefaults(config))
+	if err != nil {
+		return config, err
+	}
+	ours, err := decodeValue(oursData)
+	if err != nil {
+		return config, err
+	}
+
+	var conflicts []string
+	merged := mergeValues("", base, ours, theirs, &conflicts)
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return config, fmt.Errorf("config file was changed on disk and conflicts with your edits at: %s. Reload the config and apply your changes again", strings.Join(conflicts, ", "))
+	}
+
+	mergedData, err := json.Marshal(merged)
+	if err != nil {
+		return config, err
+	}
+	var result Config
+	if err := json.Unmarshal(mergedData, &result); err != nil {
+		return config, err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Merged external config changes without conflicts")
+	}
+
+	return result, nil
+}
+
+// canonicalConfig decodes raw config content through the schema and back, so
+// values written in different but equivalent forms compare equal
+func (a *App) canonicalConfig(data []byte) (interface{}, error) {
+	var config Config
+	if data != nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	canonical, err := json.Marshal(a.applyConfigDefaults(config))
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(canonical)
+}
+
+// mergeValues performs a three-way merge of decoded JSON values. Objects are
+// merged key by key and the Providers list is merged by provider name; any
+// other value changed differently on both sides is recorded as a conflict.
+func mergeValues(path string, base, ours, theirs interface{}, conflicts *[]string) interface{} {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours
+	case reflect.DeepEqual(base, ours):
+		return theirs
+	case reflect.DeepEqual(base, theirs):
+		return ours
+	}
+
+	if path == "Providers" {
+		if merged, ok := mergeProviders(base, ours, theirs, conflicts); ok {
+			return merged
+		}
+	}
+
+	baseMap, baseOK := base.(map[string]interface{})
+	oursMap, oursOK := ours.(map[string]interface{})
+	theirsMap, theirsOK := theirs.(map[string]interface{})
+	if oursOK && theirsOK {
+		if !baseOK {
+			baseMap = map[string]interface{}{}
+		}
+		return mergeMaps(path, baseMap, oursMap, theirsMap, conflicts)
+	}
+
+	if path == "" {
+		path = "(root)"
+	}
+	*conflicts = append(*conflicts, path)
+	return ours
+}
+
+// missingValue marks a key absent from one side of a merge
+type missingValue struct{}
+
+// mergeMaps merges objects key by key
+func mergeMaps(path string, base, ours, theirs map[string]interface{}, conflicts *[]string) map[string]interface{} {
+	keys := make(map[string]bool)
+	for _, m := range []map[string]interface{}{base, ours, theirs} {
+		for key := range m {
+			keys[key] = true
+		}
+	}
+
+	lookup := func(m map[string]interface{}, key string) interface{} {
+		if value, ok := m[key]; ok {
+			return value
+		}
+		return missingValue{}
+	}
+
+	result := make(map[string]interface{})
+	for key := range keys {
+		value := mergeValues(joinPath(path, key), lookup(base, key), lookup(ours, key), lookup(theirs, key), conflicts)
+		if _, missing := value.(missingValue); !missing {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// mergeProviders merges provider lists by name, keeping the order of ours and
+// appending providers only added on disk
+func mergeProviders(base, ours, theirs interface{}, conflicts *[]string) (interface{}, bool) {
+	index := func(value interface{}) (map[string]interface{}, []string, bool) {
+		result := make(map[string]interface{})
+		var order []string
+		if _, missing := value.(missingValue); missing || value == nil {
+			return result, order, true
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		for _, item := range list {
+			provider, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, nil, false
+			}
+			name, ok := provider["name"].(string)
+			if !ok {
+				return nil, nil, false
+			}
+			if _, duplicate := result[name]; duplicate {
+				return nil, nil, false
+			}
+			result[name] = provider
+			order = append(order, name)
+		}
+		return result, order, true
+	}
+
+	baseIndex, _, ok1 := index(base)
+	oursIndex, oursOrder, ok2 := index(ours)
+	theirsIndex, theirsOrder, ok3 := index(theirs)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, false
+	}
+
+	merged := mergeMaps("Providers", baseIndex, oursIndex, theirsIndex, conflicts)
+
+	var result []interface{}
+	for _, name := range append(oursOrder, theirsOrder...) {
+		if provider, ok := merged[name]; ok {
+			result = append(result, provider)
+			delete(merged, name)
+		}
+	}
+	return result, true
+}
+
+// startConfigWatcher starts watching config.json for external changes
+func (a *App) startConfigWatcher() {
+	configPath := a.GetConfigPath()
+	if configPath == "" {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: Failed to create config watcher: %v", err)
+		}
+		return
+	}
+
+	// 监听目录而不是文件本身，编辑器通常通过重命名替换文件
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0755); err == nil {
+		err = watcher.Add(dir)
+	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: Failed to watch %s: %v", dir, err)
+		}
+		watcher.Close()
+		return
+	}
+
+	a.watcher = &configWatcher{watcher: watcher, done: make(chan struct{})}
+	go a.watchConfig(a.watcher, filepath.Base(configPath))
+
+	if a.logger != nil {
+		a.logger.Printf("Watching %s for external changes", configPath)
+	}
+}
+
+// stopConfigWatcher stops the config watcher
+func (a *App) stopConfigWatcher() {
+	if a.watcher == nil {
+		return
+	}
+	close(a.watcher.done)
+	a.watcher.watcher.Close()
+	a.watcher = nil
+}
+
+// watchConfig handles watcher events until the watcher is stopped
+func (a *App) watchConfig(w *configWatcher, name string) {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != name {
+				continue
+			}
+			w.mu.Lock()
+			if w.timer != nil {
+				w.timer.Stop()
+			}
+			w.timer = time.AfterFunc(configWatchDebounce, func() { a.checkExternalChange(w) })
+			w.mu.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			if a.logger != nil {
+				a.logger.Printf("WARNING: Config watcher error: %v", err)
+			}
+		}
+	}
+}
+
+// checkExternalChange emits EventConfigChanged when config.json no longer
+// matches what the UI loaded
+func (a *App) checkExternalChange(w *configWatcher) {
+	status, err := a.GetConfigFileStatus()
+	if err != nil || !status.Diverged {
+		return
+	}
+
+	// 同一内容只通知一次
+	w.mu.Lock()
+	if w.notifiedHash == status.DiskHash {
+		w.mu.Unlock()
+		return
+	}
+	w.notifiedHash = status.DiskHash
+	w.mu.Unlock()
+
+	if a.logger != nil {
+		a.logger.Printf("Config file changed on disk: %s", status.Path)
+	}
+
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, EventConfigChanged, status)
+	}
+}
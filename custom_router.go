@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// nodeCheckTimeout bounds a `node --check` run
+const nodeCheckTimeout = 10 * time.Second
+
+// CustomRouterStatus reports the state of the script set in CUSTOM_ROUTER_PATH
+type CustomRouterStatus struct {
+	Path          string `json:"path"`
+	ResolvedPath  string `json:"resolvedPath"`
+	Exists        bool   `json:"exists"`
+	ModTime       string `json:"modTime,omitempty"`
+	NodeAvailable bool   `json:"nodeAvailable"`
+	SyntaxChecked bool   `json:"syntaxChecked"`
+	SyntaxOK      bool   `json:"syntaxOK"`
+	SyntaxError   string `json:"syntaxError,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// syntaxCheckCache keeps the last `node --check` result so status polling
+// only runs node again after the script changes
+type syntaxCheckCache struct {
+	mu     sync.Mutex
+	key    string
+	ok     bool
+	output string
+}
+
+// customRouterTemplate is the starter script written by ScaffoldCustomRouter
+var customRouterTemplate = template.Must(template.New("custom-router").Funcs(template.FuncMap{
+	"json": func(value interface{}) string {
+		data, _ := json.Marshal(value)
+		return string(data)
+	},
+}).Parse(`// Custom router for claude-code-router.
+//
+// CCR calls this function for every request before applying the Router
+// settings. Return a "provider,model" string to choose the route, or null to
+// fall back to the Router settings in config.json.
+
+// Router settings when this script was created
+const ROUTES = {
+{{- range .Slots}}
+  {{json .Name}}: {{json .Value}},
+{{- end}}
+};
+
+const LONG_CONTEXT_THRESHOLD = {{.Threshold}};
+
+module.exports = async function router(req, config) {
+  const body = req.body || {};
+  const messages = body.messages || [];
+
+  // Example: send requests that ask for an explanation to the think model
+  // const last = messages[messages.length - 1];
+  // if (last && typeof last.content === "string" && last.content.includes("explain")) {
+  //   return ROUTES.think || null;
+  // }
+
+  return null;
+};
+`))
+
+// routerScriptSlot is a Router slot rendered into the starter script
+type routerScriptSlot struct {
+	Name  string
+	Value string
+}
+
+// defaultCustomRouterPath returns where a starter router script is created
+// when no path is given
+func defaultCustomRouterPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "custom-router.js")
+}
+
+// renderCustomRouter builds the starter script from the current Router slots
+func renderCustomRouter(router Router) ([]byte, error) {
+	data := struct {
+		Slots     []routerScriptSlot
+		Threshold int
+	}{Threshold: router.LongContextThreshold}
+	if data.Threshold <= 0 {
+		data.Threshold = defaultLongContextThreshold
+	}
+	for _, slot := range router.slotNames() {
+		data.Slots = append(data.Slots, routerScriptSlot{Name: slot, Value: router.slot(slot)})
+	}
+
+	var buf bytes.Buffer
+	if err := customRouterTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// findNode locates the node executable, preferring the one installed next to
+// ccr in NPM_GLOBAL_PREFIX
+func findNode(config Config) string {
+	if config.NPM_GLOBAL_PREFIX != "" {
+		candidates := []string{filepath.Join(config.NPM_GLOBAL_PREFIX, "node")}
+		if runtime.GOOS == "windows" {
+			candidates = []string{filepath.Join(config.NPM_GLOBAL_PREFIX, "node.exe")}
+		} else {
+			candidates = append(candidates, filepath.Join(config.NPM_GLOBAL_PREFIX, "bin", "node"))
+		}
+		for _, candidate := range candidates {
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+	}
+
+	if path, err := exec.LookPath("node"); err == nil {
+		return path
+	}
+	return ""
+}
+
+// checkScriptSyntax runs `node --check` on path, reusing the previous result
+// while the file is unchanged
+func (a *App) checkScriptSyntax(node, path string, modTime time.Time, size int64) (bool, string) {
+	key := fmt.Sprintf("%s|%s|%d|%d", node, path, modTime.UnixNano(), size)
+
+	a.routerCheck.mu.Lock()
+	defer a.routerCheck.mu.Unlock()
+	if a.routerCheck.key == key {
+		return a.routerCheck.ok, a.routerCheck.output
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nodeCheckTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, node, "--check", path)
+	cmd.SysProcAttr = getSysProcAttr()
+	output, err := cmd.CombinedOutput()
+
+	ok := err == nil
+	message := strings.TrimSpace(string(output))
+	if !ok && message == "" {
+		message = err.Error()
+	}
+
+	a.routerCheck.key = key
+	a.routerCheck.ok = ok
+	a.routerCheck.output = message
+	return ok, message
+}
+
+// customRouterStatus inspects the script set in CUSTOM_ROUTER_PATH
+func (a *App) customRouterStatus(config Config) CustomRouterStatus {
+	status := CustomRouterStatus{
+		Path:         config.CUSTOM_ROUTER_PATH,
+		ResolvedPath: resolveScriptPath(config.CUSTOM_ROUTER_PATH),
+	}
+	if config.CUSTOM_ROUTER_PATH == "" {
+		return status
+	}
+
+	info, err := os.Stat(status.ResolvedPath)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if info.IsDir() {
+		status.Error = "custom router path is a directory"
+		return status
+	}
+	status.Exists = true
+	status.ModTime = info.ModTime().Format(time.RFC3339)
+
+	node := findNode(config)
+	if node == "" {
+		return status
+	}
+	status.NodeAvailable = true
+	status.SyntaxChecked = true
+	status.SyntaxOK, status.SyntaxError = a.checkScriptSyntax(node, status.ResolvedPath, info.ModTime(), info.Size())
+	if status.SyntaxOK {
+		status.SyntaxError = ""
+	}
+	return status
+}
+
+// validateCustomRouter checks CUSTOM_ROUTER_PATH
+func validateCustomRouter(config Config, add func(path, severity, format string, args ...interface{})) {
+	if config.CUSTOM_ROUTER_PATH == "" {
+		return
+	}
+
+	resolved := resolveScriptPath(config.CUSTOM_ROUTER_PATH)
+	if !filepath.IsAbs(resolved) {
+		add("CUSTOM_ROUTER_PATH", SeverityWarning, "path is relative and depends on the directory CCR is started from")
+	}
+	if ext := strings.ToLower(filepath.Ext(resolved)); ext != ".js" && ext != ".cjs" {
+		add("CUSTOM_ROUTER_PATH", SeverityWarning, "custom router should be a .js file")
+	}
+
+	info, err := os.Stat(resolved)
+	switch {
+	case err != nil:
+		add("CUSTOM_ROUTER_PATH", SeverityWarning, "custom router script cannot be read: %v", err)
+	case info.IsDir():
+		add("CUSTOM_ROUTER_PATH", SeverityError, "custom router path is a directory")
+	}
+}
+
+// GetCustomRouterStatus reports whether the script in CUSTOM_ROUTER_PATH of
+// config exists, when it was last modified and whether node accepts its syntax
+func (a *App) GetCustomRouterStatus(config Config) CustomRouterStatus {
+	return a.customRouterStatus(config)
+}
+
+// ScaffoldCustomRouter writes a starter router script reflecting the Router
+// slots of config and returns its path. An empty path uses
+// custom-router.js in the CCR directory. Existing files are not overwritten;
+// the caller sets CUSTOM_ROUTER_PATH and saves the config.
+func (a *App) ScaffoldCustomRouter(config Config, path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = defaultCustomRouterPath()
+		if path == "" {
+			return "", fmt.Errorf("could not determine custom router path")
+		}
+	}
+	resolved := resolveScriptPath(path)
+
+	if _, err := os.Stat(resolved); err == nil {
+		return "", fmt.Errorf("%s already exists", resolved)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	script, err := renderCustomRouter(config.Router)
+	if err != nil {
+		return "", fmt.Errorf("failed to render custom router: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := writeFileAtomic(resolved, script, 0644); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write custom router %s: %v", resolved, err)
+		}
+		return "", fmt.Errorf("failed to write custom router: %v", err)
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Created custom router script %s", resolved)
+	}
+	return resolved, nil
+}
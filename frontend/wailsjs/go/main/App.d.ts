@@ -2,44 +2,178 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function ActivateLayeredProfile(arg1:string,arg2:Array<string>,arg3:boolean):Promise<void>;
+
+export function ActivateProfile(arg1:string,arg2:boolean):Promise<void>;
+
+export function AddSecret(arg1:string,arg2:string):Promise<string>;
+
+export function AddTransformer(arg1:main.ProviderTransformer,arg2:string,arg3:main.TransformerRef):Promise<main.ProviderTransformer>;
+
+export function ApplyConfig(arg1:main.Config):Promise<main.ApplyReport>;
+
+export function ApplyImport(arg1:string,arg2:main.ImportSelection):Promise<void>;
+
+export function ChangeVaultPassphrase(arg1:string,arg2:string):Promise<void>;
+
 export function ClearAppLogs():Promise<void>;
 
+export function ClearCrashHistory():Promise<void>;
+
 export function ClearLogs():Promise<void>;
 
 export function CompareVersions(arg1:string,arg2:string):Promise<boolean>;
 
+export function ComposeConfig(arg1:string,arg2:Array<string>):Promise<main.LayeredConfig>;
+
+export function CreateProviderFromPreset(arg1:string,arg2:main.Provider):Promise<main.Provider>;
+
+export function CreateVault(arg1:string):Promise<void>;
+
+export function DeleteOverlay(arg1:string):Promise<void>;
+
+export function DeleteProfile(arg1:string):Promise<void>;
+
+export function DeleteSecret(arg1:string):Promise<void>;
+
+export function DiffConfigRevisions(arg1:string,arg2:string):Promise<Array<main.ConfigChange>>;
+
+export function DiscoverModels(arg1:string):Promise<main.ModelDiscovery>;
+
 export function DownloadUpdate(arg1:string):Promise<string>;
 
+export function ExportConfig(arg1:main.ExportOptions):Promise<main.ExportResult>;
+
 export function GetAppLogPath():Promise<string>;
 
 export function GetAppVersion():Promise<string>;
 
+export function GetBackupDir():Promise<string>;
+
 export function GetCCRVersion():Promise<string>;
 
+export function GetConfigFileStatus():Promise<main.ConfigFileStatus>;
+
 export function GetConfigPath():Promise<string>;
 
+export function GetCrashHistoryPath():Promise<string>;
+
+export function GetCustomRouterStatus(arg1:main.Config):Promise<main.CustomRouterStatus>;
+
+export function GetEnvReferences(arg1:main.Config):Promise<Array<main.EnvReference>>;
+
+export function GetExportsDir():Promise<string>;
+
+export function GetHistoryDir():Promise<string>;
+
 export function GetLatestVersionFromGitHub():Promise<string>;
 
 export function GetLogPath():Promise<string>;
 
+export function GetManagerSettings():Promise<main.ManagerSettings>;
+
+export function GetOverlay(arg1:string):Promise<string>;
+
+export function GetOverlaysDir():Promise<string>;
+
+export function GetPIDFilePath():Promise<string>;
+
+export function GetPluginsDir():Promise<string>;
+
+export function GetPresetsDir():Promise<string>;
+
+export function GetProfilesDir():Promise<string>;
+
+export function GetRoutersDir():Promise<string>;
+
+export function GetServiceOutput():Promise<Array<main.OutputLine>>;
+
 export function GetServiceStatus():Promise<main.ServiceStatus>;
 
+export function GetSettingsPath():Promise<string>;
+
+export function GetSupervisorStatus():Promise<main.SupervisorStatus>;
+
+export function GetVaultPath():Promise<string>;
+
+export function GetVaultStatus():Promise<main.VaultStatus>;
+
+export function GetWatchdogStatus():Promise<main.WatchdogStatus>;
+
 export function Greet(arg1:string):Promise<string>;
 
+export function ImportConfig(arg1:string):Promise<main.ImportPreview>;
+
+export function ListConfigBackups():Promise<Array<main.ConfigBackup>>;
+
+export function ListConfigRevisions():Promise<Array<main.ConfigRevision>>;
+
+export function ListOverlays():Promise<Array<main.OverlayInfo>>;
+
+export function ListProfiles():Promise<Array<main.ProfileInfo>>;
+
+export function ListProviderPresets():Promise<Array<main.ProviderPreset>>;
+
+export function ListSecrets():Promise<Array<main.SecretInfo>>;
+
+export function ListTransformerPlugins(arg1:main.Config):Promise<Array<main.TransformerPluginStatus>>;
+
+export function ListTransformers(arg1:main.Config):Promise<Array<main.TransformerInfo>>;
+
 export function LoadConfig():Promise<main.Config>;
 
+export function LockVault():Promise<void>;
+
+export function MoveTransformer(arg1:main.ProviderTransformer,arg2:string,arg3:number,arg4:number):Promise<main.ProviderTransformer>;
+
 export function ReadAppLogs():Promise<string>;
 
 export function ReadLogs():Promise<string>;
 
 export function ReadREADME():Promise<string>;
 
+export function RegisterTransformerPlugin(arg1:main.Config,arg2:string,arg3:Record<string, any>):Promise<main.Config>;
+
+export function RemoveTransformer(arg1:main.ProviderTransformer,arg2:string,arg3:number):Promise<main.ProviderTransformer>;
+
+export function RenameProvider(arg1:main.Config,arg2:string,arg3:string):Promise<main.Config>;
+
+export function ResolveRoutes(arg1:main.Config):Promise<Array<main.RouteResolution>>;
+
 export function RestartService():Promise<void>;
 
+export function RestoreConfigBackup(arg1:string):Promise<void>;
+
+export function RevealConfigSecret(arg1:string):Promise<string>;
+
+export function RevealSecret(arg1:string,arg2:string):Promise<string>;
+
+export function RotateSecret(arg1:string,arg2:string):Promise<void>;
+
+export function SaveAsProfile(arg1:string,arg2:boolean):Promise<void>;
+
 export function SaveConfig(arg1:main.Config):Promise<void>;
 
+export function SaveManagerSettings(arg1:main.ManagerSettings):Promise<void>;
+
+export function SaveOverlay(arg1:string,arg2:string):Promise<void>;
+
+export function ScaffoldCustomRouter(arg1:main.Config,arg2:string):Promise<string>;
+
+export function SimulateRoute(arg1:main.Config,arg2:main.RouteRequest):Promise<main.RouteSimulation>;
+
 export function StartService():Promise<void>;
 
 export function StopService():Promise<void>;
 
 export function TestLogging():Promise<string>;
+
+export function TestProvider(arg1:string):Promise<main.ProviderTestResult>;
+
+export function UnlockVault(arg1:string):Promise<void>;
+
+export function UnregisterTransformerPlugin(arg1:main.Config,arg2:string):Promise<main.Config>;
+
+export function ValidateConfig(arg1:main.Config):Promise<Array<main.ValidationIssue>>;
+
+export function ValidateConfigFile():Promise<Array<main.ValidationIssue>>;
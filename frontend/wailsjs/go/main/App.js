@@ -2,10 +2,42 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ActivateLayeredProfile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ActivateLayeredProfile'](arg1, arg2, arg3);
+}
+
+export function ActivateProfile(arg1, arg2) {
+  return window['go']['main']['App']['ActivateProfile'](arg1, arg2);
+}
+
+export function AddSecret(arg1, arg2) {
+  return window['go']['main']['App']['AddSecret'](arg1, arg2);
+}
+
+export function AddTransformer(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddTransformer'](arg1, arg2, arg3);
+}
+
+export function ApplyConfig(arg1) {
+  return window['go']['main']['App']['ApplyConfig'](arg1);
+}
+
+export function ApplyImport(arg1, arg2) {
+  return window['go']['main']['App']['ApplyImport'](arg1, arg2);
+}
+
+export function ChangeVaultPassphrase(arg1, arg2) {
+  return window['go']['main']['App']['ChangeVaultPassphrase'](arg1, arg2);
+}
+
 export function ClearAppLogs() {
   return window['go']['main']['App']['ClearAppLogs']();
 }
 
+export function ClearCrashHistory() {
+  return window['go']['main']['App']['ClearCrashHistory']();
+}
+
 export function ClearLogs() {
   return window['go']['main']['App']['ClearLogs']();
 }
@@ -14,10 +46,46 @@ export function CompareVersions(arg1, arg2) {
   return window['go']['main']['App']['CompareVersions'](arg1, arg2);
 }
 
+export function ComposeConfig(arg1, arg2) {
+  return window['go']['main']['App']['ComposeConfig'](arg1, arg2);
+}
+
+export function CreateProviderFromPreset(arg1, arg2) {
+  return window['go']['main']['App']['CreateProviderFromPreset'](arg1, arg2);
+}
+
+export function CreateVault(arg1) {
+  return window['go']['main']['App']['CreateVault'](arg1);
+}
+
+export function DeleteOverlay(arg1) {
+  return window['go']['main']['App']['DeleteOverlay'](arg1);
+}
+
+export function DeleteProfile(arg1) {
+  return window['go']['main']['App']['DeleteProfile'](arg1);
+}
+
+export function DeleteSecret(arg1) {
+  return window['go']['main']['App']['DeleteSecret'](arg1);
+}
+
+export function DiffConfigRevisions(arg1, arg2) {
+  return window['go']['main']['App']['DiffConfigRevisions'](arg1, arg2);
+}
+
+export function DiscoverModels(arg1) {
+  return window['go']['main']['App']['DiscoverModels'](arg1);
+}
+
 export function DownloadUpdate(arg1) {
   return window['go']['main']['App']['DownloadUpdate'](arg1);
 }
 
+export function ExportConfig(arg1) {
+  return window['go']['main']['App']['ExportConfig'](arg1);
+}
+
 export function GetAppLogPath() {
   return window['go']['main']['App']['GetAppLogPath']();
 }
@@ -26,14 +94,42 @@ export function GetAppVersion() {
   return window['go']['main']['App']['GetAppVersion']();
 }
 
+export function GetBackupDir() {
+  return window['go']['main']['App']['GetBackupDir']();
+}
+
 export function GetCCRVersion() {
   return window['go']['main']['App']['GetCCRVersion']();
 }
 
+export function GetConfigFileStatus() {
+  return window['go']['main']['App']['GetConfigFileStatus']();
+}
+
 export function GetConfigPath() {
   return window['go']['main']['App']['GetConfigPath']();
 }
 
+export function GetCrashHistoryPath() {
+  return window['go']['main']['App']['GetCrashHistoryPath']();
+}
+
+export function GetCustomRouterStatus(arg1) {
+  return window['go']['main']['App']['GetCustomRouterStatus'](arg1);
+}
+
+export function GetEnvReferences(arg1) {
+  return window['go']['main']['App']['GetEnvReferences'](arg1);
+}
+
+export function GetExportsDir() {
+  return window['go']['main']['App']['GetExportsDir']();
+}
+
+export function GetHistoryDir() {
+  return window['go']['main']['App']['GetHistoryDir']();
+}
+
 export function GetLatestVersionFromGitHub() {
   return window['go']['main']['App']['GetLatestVersionFromGitHub']();
 }
@@ -42,18 +138,118 @@ export function GetLogPath() {
   return window['go']['main']['App']['GetLogPath']();
 }
 
+export function GetManagerSettings() {
+  return window['go']['main']['App']['GetManagerSettings']();
+}
+
+export function GetOverlay(arg1) {
+  return window['go']['main']['App']['GetOverlay'](arg1);
+}
+
+export function GetOverlaysDir() {
+  return window['go']['main']['App']['GetOverlaysDir']();
+}
+
+export function GetPIDFilePath() {
+  return window['go']['main']['App']['GetPIDFilePath']();
+}
+
+export function GetPluginsDir() {
+  return window['go']['main']['App']['GetPluginsDir']();
+}
+
+export function GetPresetsDir() {
+  return window['go']['main']['App']['GetPresetsDir']();
+}
+
+export function GetProfilesDir() {
+  return window['go']['main']['App']['GetProfilesDir']();
+}
+
+export function GetRoutersDir() {
+  return window['go']['main']['App']['GetRoutersDir']();
+}
+
+export function GetServiceOutput() {
+  return window['go']['main']['App']['GetServiceOutput']();
+}
+
 export function GetServiceStatus() {
   return window['go']['main']['App']['GetServiceStatus']();
 }
 
+export function GetSettingsPath() {
+  return window['go']['main']['App']['GetSettingsPath']();
+}
+
+export function GetSupervisorStatus() {
+  return window['go']['main']['App']['GetSupervisorStatus']();
+}
+
+export function GetVaultPath() {
+  return window['go']['main']['App']['GetVaultPath']();
+}
+
+export function GetVaultStatus() {
+  return window['go']['main']['App']['GetVaultStatus']();
+}
+
+export function GetWatchdogStatus() {
+  return window['go']['main']['App']['GetWatchdogStatus']();
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
 
+export function ImportConfig(arg1) {
+  return window['go']['main']['App']['ImportConfig'](arg1);
+}
+
+export function ListConfigBackups() {
+  return window['go']['main']['App']['ListConfigBackups']();
+}
+
+export function ListConfigRevisions() {
+  return window['go']['main']['App']['ListConfigRevisions']();
+}
+
+export function ListOverlays() {
+  return window['go']['main']['App']['ListOverlays']();
+}
+
+export function ListProfiles() {
+  return window['go']['main']['App']['ListProfiles']();
+}
+
+export function ListProviderPresets() {
+  return window['go']['main']['App']['ListProviderPresets']();
+}
+
+export function ListSecrets() {
+  return window['go']['main']['App']['ListSecrets']();
+}
+
+export function ListTransformerPlugins(arg1) {
+  return window['go']['main']['App']['ListTransformerPlugins'](arg1);
+}
+
+export function ListTransformers(arg1) {
+  return window['go']['main']['App']['ListTransformers'](arg1);
+}
+
 export function LoadConfig() {
   return window['go']['main']['App']['LoadConfig']();
 }
 
+export function LockVault() {
+  return window['go']['main']['App']['LockVault']();
+}
+
+export function MoveTransformer(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['MoveTransformer'](arg1, arg2, arg3, arg4);
+}
+
 export function ReadAppLogs() {
   return window['go']['main']['App']['ReadAppLogs']();
 }
@@ -66,14 +262,66 @@ export function ReadREADME() {
   return window['go']['main']['App']['ReadREADME']();
 }
 
+export function RegisterTransformerPlugin(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RegisterTransformerPlugin'](arg1, arg2, arg3);
+}
+
+export function RemoveTransformer(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RemoveTransformer'](arg1, arg2, arg3);
+}
+
+export function RenameProvider(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RenameProvider'](arg1, arg2, arg3);
+}
+
+export function ResolveRoutes(arg1) {
+  return window['go']['main']['App']['ResolveRoutes'](arg1);
+}
+
 export function RestartService() {
   return window['go']['main']['App']['RestartService']();
 }
 
+export function RestoreConfigBackup(arg1) {
+  return window['go']['main']['App']['RestoreConfigBackup'](arg1);
+}
+
+export function RevealConfigSecret(arg1) {
+  return window['go']['main']['App']['RevealConfigSecret'](arg1);
+}
+
+export function RevealSecret(arg1, arg2) {
+  return window['go']['main']['App']['RevealSecret'](arg1, arg2);
+}
+
+export function RotateSecret(arg1, arg2) {
+  return window['go']['main']['App']['RotateSecret'](arg1, arg2);
+}
+
+export function SaveAsProfile(arg1, arg2) {
+  return window['go']['main']['App']['SaveAsProfile'](arg1, arg2);
+}
+
 export function SaveConfig(arg1) {
   return window['go']['main']['App']['SaveConfig'](arg1);
 }
 
+export function SaveManagerSettings(arg1) {
+  return window['go']['main']['App']['SaveManagerSettings'](arg1);
+}
+
+export function SaveOverlay(arg1, arg2) {
+  return window['go']['main']['App']['SaveOverlay'](arg1, arg2);
+}
+
+export function ScaffoldCustomRouter(arg1, arg2) {
+  return window['go']['main']['App']['ScaffoldCustomRouter'](arg1, arg2);
+}
+
+export function SimulateRoute(arg1, arg2) {
+  return window['go']['main']['App']['SimulateRoute'](arg1, arg2);
+}
+
 export function StartService() {
   return window['go']['main']['App']['StartService']();
 }
@@ -85,3 +333,23 @@ export function StopService() {
 export function TestLogging() {
   return window['go']['main']['App']['TestLogging']();
 }
+
+export function TestProvider(arg1) {
+  return window['go']['main']['App']['TestProvider'](arg1);
+}
+
+export function UnlockVault(arg1) {
+  return window['go']['main']['App']['UnlockVault'](arg1);
+}
+
+export function UnregisterTransformerPlugin(arg1, arg2) {
+  return window['go']['main']['App']['UnregisterTransformerPlugin'](arg1, arg2);
+}
+
+export function ValidateConfig(arg1) {
+  return window['go']['main']['App']['ValidateConfig'](arg1);
+}
+
+export function ValidateConfigFile() {
+  return window['go']['main']['App']['ValidateConfigFile']();
+}
@@ -1,15 +1,337 @@
 export namespace main {
 	
+	export class ActiveProfile {
+	    name: string;
+	    activatedAt: string;
+	    overlays?: string[];
+	    hash: string;
+	    modified: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ActiveProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.activatedAt = source["activatedAt"];
+	        this.overlays = source["overlays"];
+	        this.hash = source["hash"];
+	        this.modified = source["modified"];
+	    }
+	}
+	export class HealthProbe {
+	    state: string;
+	    url: string;
+	    statusCode?: number;
+	    latencyMs: number;
+	    version?: string;
+	    startedAt?: string;
+	    uptimeSeconds?: number;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HealthProbe(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.state = source["state"];
+	        this.url = source["url"];
+	        this.statusCode = source["statusCode"];
+	        this.latencyMs = source["latencyMs"];
+	        this.version = source["version"];
+	        this.startedAt = source["startedAt"];
+	        this.uptimeSeconds = source["uptimeSeconds"];
+	        this.message = source["message"];
+	    }
+	}
+	export class ApplyStep {
+	    name: string;
+	    status: string;
+	    message?: string;
+	    durationMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ApplyStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.status = source["status"];
+	        this.message = source["message"];
+	        this.durationMs = source["durationMs"];
+	    }
+	}
+	export class ConfigChange {
+	    kind: string;
+	    path: string;
+	    before?: string;
+	    after?: string;
+	    added?: string[];
+	    removed?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigChange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.path = source["path"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	        this.added = source["added"];
+	        this.removed = source["removed"];
+	    }
+	}
+	export class ValidationIssue {
+	    path: string;
+	    severity: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ValidationIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.severity = source["severity"];
+	        this.message = source["message"];
+	    }
+	}
+	export class ApplyReport {
+	    outcome: string;
+	    issues: ValidationIssue[];
+	    changes: ConfigChange[];
+	    steps: ApplyStep[];
+	    health: HealthProbe;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ApplyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.outcome = source["outcome"];
+	        this.issues = this.convertValues(source["issues"], ValidationIssue);
+	        this.changes = this.convertValues(source["changes"], ConfigChange);
+	        this.steps = this.convertValues(source["steps"], ApplyStep);
+	        this.health = this.convertValues(source["health"], HealthProbe);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class CheckStep {
+	    ok: boolean;
+	    skipped: boolean;
+	    durationMs: number;
+	    detail?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CheckStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ok = source["ok"];
+	        this.skipped = source["skipped"];
+	        this.durationMs = source["durationMs"];
+	        this.detail = source["detail"];
+	        this.error = source["error"];
+	    }
+	}
+	export class TransformerPlugin {
+	    path: string;
+	    options?: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransformerPlugin(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.options = source["options"];
+	    }
+	}
+	export class Router {
+	    default?: string;
+	    background?: string;
+	    think?: string;
+	    longContext?: string;
+	    longContextThreshold?: number;
+	    webSearch?: string;
+	    image?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Router(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.default = source["default"];
+	        this.background = source["background"];
+	        this.think = source["think"];
+	        this.longContext = source["longContext"];
+	        this.longContextThreshold = source["longContextThreshold"];
+	        this.webSearch = source["webSearch"];
+	        this.image = source["image"];
+	    }
+	}
+	export class ModelTransformer {
+	    model: string;
+	    use: TransformerRef[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelTransformer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.use = this.convertValues(source["use"], TransformerRef);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TransformerRef {
+	    name: string;
+	    options?: Record<string, any>;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransformerRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.options = source["options"];
+	    }
+	}
+	export class ProviderTransformer {
+	    use: TransformerRef[];
+	    models: ModelTransformer[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderTransformer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.use = this.convertValues(source["use"], TransformerRef);
+	        this.models = this.convertValues(source["models"], ModelTransformer);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Provider {
+	    name: string;
+	    api_base_url: string;
+	    api_key: string;
+	    models: string[];
+	    transformer?: ProviderTransformer;
+	    savedName?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Provider(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.api_base_url = source["api_base_url"];
+	        this.api_key = source["api_key"];
+	        this.models = source["models"];
+	        this.transformer = this.convertValues(source["transformer"], ProviderTransformer);
+	        this.savedName = source["savedName"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Config {
-	    APIKEY?: any;
-	    PROXY_URL?: any;
-	    HOST?: any;
-	    PORT?: any;
-	    API_TIMEOUT_MS?: any;
-	    LOG?: any;
-	    NPM_GLOBAL_PREFIX?: any;
-	    Providers?: any;
-	    Router?: any;
+	    APIKEY?: string;
+	    PROXY_URL?: string;
+	    HOST?: string;
+	    PORT?: number;
+	    API_TIMEOUT_MS?: number;
+	    LOG: boolean;
+	    NPM_GLOBAL_PREFIX?: string;
+	    CUSTOM_ROUTER_PATH?: string;
+	    Providers?: Provider[];
+	    Router: Router;
+	    fallback?: Record<string, Array<string>>;
+	    transformers?: TransformerPlugin[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Config(source);
@@ -24,13 +346,814 @@ export namespace main {
 	        this.API_TIMEOUT_MS = source["API_TIMEOUT_MS"];
 	        this.LOG = source["LOG"];
 	        this.NPM_GLOBAL_PREFIX = source["NPM_GLOBAL_PREFIX"];
-	        this.Providers = source["Providers"];
-	        this.Router = source["Router"];
+	        this.CUSTOM_ROUTER_PATH = source["CUSTOM_ROUTER_PATH"];
+	        this.Providers = this.convertValues(source["Providers"], Provider);
+	        this.Router = this.convertValues(source["Router"], Router);
+	        this.fallback = source["fallback"];
+	        this.transformers = this.convertValues(source["transformers"], TransformerPlugin);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ConfigBackup {
+	    id: string;
+	    createdAt: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigBackup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.createdAt = source["createdAt"];
+	        this.size = source["size"];
+	    }
+	}
+	
+	export class ConfigFileStatus {
+	    path: string;
+	    exists: boolean;
+	    modTime?: string;
+	    loadedHash?: string;
+	    diskHash?: string;
+	    diverged: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigFileStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.exists = source["exists"];
+	        this.modTime = source["modTime"];
+	        this.loadedHash = source["loadedHash"];
+	        this.diskHash = source["diskHash"];
+	        this.diverged = source["diverged"];
+	    }
+	}
+	export class ConfigRevision {
+	    id: string;
+	    timestamp: string;
+	    author: string;
+	    summary: string;
+	    schemaVersion: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConfigRevision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.timestamp = source["timestamp"];
+	        this.author = source["author"];
+	        this.summary = source["summary"];
+	        this.schemaVersion = source["schemaVersion"];
+	    }
+	}
+	export class CrashRecord {
+	    time: string;
+	    mode: string;
+	    pid?: number;
+	    exitCode: number;
+	    reason: string;
+	    output: string[];
+	    restartDelayMs?: number;
+	    gaveUp?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CrashRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = source["time"];
+	        this.mode = source["mode"];
+	        this.pid = source["pid"];
+	        this.exitCode = source["exitCode"];
+	        this.reason = source["reason"];
+	        this.output = source["output"];
+	        this.restartDelayMs = source["restartDelayMs"];
+	        this.gaveUp = source["gaveUp"];
+	    }
+	}
+	export class CustomRouterStatus {
+	    path: string;
+	    resolvedPath: string;
+	    exists: boolean;
+	    modTime?: string;
+	    nodeAvailable: boolean;
+	    syntaxChecked: boolean;
+	    syntaxOK: boolean;
+	    syntaxError?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustomRouterStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.resolvedPath = source["resolvedPath"];
+	        this.exists = source["exists"];
+	        this.modTime = source["modTime"];
+	        this.nodeAvailable = source["nodeAvailable"];
+	        this.syntaxChecked = source["syntaxChecked"];
+	        this.syntaxOK = source["syntaxOK"];
+	        this.syntaxError = source["syntaxError"];
+	        this.error = source["error"];
+	    }
+	}
+	export class DiscoveredModel {
+	    id: string;
+	    displayName?: string;
+	    ownedBy?: string;
+	    created?: string;
+	    contextLength?: number;
+	    configured: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiscoveredModel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.displayName = source["displayName"];
+	        this.ownedBy = source["ownedBy"];
+	        this.created = source["created"];
+	        this.contextLength = source["contextLength"];
+	        this.configured = source["configured"];
+	    }
+	}
+	export class EnvReference {
+	    path: string;
+	    variable: string;
+	    resolved: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new EnvReference(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.variable = source["variable"];
+	        this.resolved = source["resolved"];
+	    }
+	}
+	export class ExportOptions {
+	    path: string;
+	    includePresets: boolean;
+	    includePlugins: boolean;
+	    includeCustomRouter: boolean;
+	    secrets: string;
+	    passphrase?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.includePresets = source["includePresets"];
+	        this.includePlugins = source["includePlugins"];
+	        this.includeCustomRouter = source["includeCustomRouter"];
+	        this.secrets = source["secrets"];
+	        this.passphrase = source["passphrase"];
+	    }
+	}
+	export class ExportResult {
+	    path: string;
+	    secrets: string[];
+	    files: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.secrets = source["secrets"];
+	        this.files = source["files"];
+	    }
+	}
+	
+	export class ImportFile {
+	    entry: string;
+	    target: string;
+	    exists: boolean;
+	    changed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.entry = source["entry"];
+	        this.target = source["target"];
+	        this.exists = source["exists"];
+	        this.changed = source["changed"];
+	    }
+	}
+	export class ImportItem {
+	    name: string;
+	    status: string;
+	    before?: string;
+	    after?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.status = source["status"];
+	        this.before = source["before"];
+	        this.after = source["after"];
+	    }
+	}
+	export class ImportPreview {
+	    path: string;
+	    createdAt: string;
+	    secrets: string;
+	    encrypted: boolean;
+	    providers: ImportItem[];
+	    slots: ImportItem[];
+	    plugins: ImportFile[];
+	    customRouter?: ImportFile;
+	    presets: ImportFile[];
+	    changes: ConfigChange[];
+	    issues: ValidationIssue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.createdAt = source["createdAt"];
+	        this.secrets = source["secrets"];
+	        this.encrypted = source["encrypted"];
+	        this.providers = this.convertValues(source["providers"], ImportItem);
+	        this.slots = this.convertValues(source["slots"], ImportItem);
+	        this.plugins = this.convertValues(source["plugins"], ImportFile);
+	        this.customRouter = this.convertValues(source["customRouter"], ImportFile);
+	        this.presets = this.convertValues(source["presets"], ImportFile);
+	        this.changes = this.convertValues(source["changes"], ConfigChange);
+	        this.issues = this.convertValues(source["issues"], ValidationIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportSelection {
+	    providers: string[];
+	    slots: string[];
+	    settings: boolean;
+	    plugins: boolean;
+	    customRouter: boolean;
+	    presets: boolean;
+	    passphrase?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportSelection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.providers = source["providers"];
+	        this.slots = source["slots"];
+	        this.settings = source["settings"];
+	        this.plugins = source["plugins"];
+	        this.customRouter = source["customRouter"];
+	        this.presets = source["presets"];
+	        this.passphrase = source["passphrase"];
+	    }
+	}
+	export class ValueSource {
+	    path: string;
+	    layer: string;
+	    index: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ValueSource(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.layer = source["layer"];
+	        this.index = source["index"];
+	    }
+	}
+	export class LayeredConfig {
+	    config: Config;
+	    layers: string[];
+	    sources: ValueSource[];
+	    issues: ValidationIssue[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LayeredConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.config = this.convertValues(source["config"], Config);
+	        this.layers = source["layers"];
+	        this.sources = this.convertValues(source["sources"], ValueSource);
+	        this.issues = this.convertValues(source["issues"], ValidationIssue);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ManagerSettings {
+	    backupRetention: number;
+	    historyRetention: number;
+	    serviceMode?: string;
+	    watchdog: boolean;
+	    watchdogMaxCrashes: number;
+	    watchdogWindowMinutes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ManagerSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.backupRetention = source["backupRetention"];
+	        this.historyRetention = source["historyRetention"];
+	        this.serviceMode = source["serviceMode"];
+	        this.watchdog = source["watchdog"];
+	        this.watchdogMaxCrashes = source["watchdogMaxCrashes"];
+	        this.watchdogWindowMinutes = source["watchdogWindowMinutes"];
+	    }
+	}
+	export class ModelCheckResult {
+	    model: string;
+	    ok: boolean;
+	    category: string;
+	    statusCode?: number;
+	    latencyMs: number;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelCheckResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.ok = source["ok"];
+	        this.category = source["category"];
+	        this.statusCode = source["statusCode"];
+	        this.latencyMs = source["latencyMs"];
+	        this.message = source["message"];
+	    }
+	}
+	export class ModelDiscovery {
+	    provider: string;
+	    endpoint: string;
+	    models: DiscoveredModel[];
+	    new: string[];
+	    missing: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ModelDiscovery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.endpoint = source["endpoint"];
+	        this.models = this.convertValues(source["models"], DiscoveredModel);
+	        this.new = source["new"];
+	        this.missing = source["missing"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class OutputLine {
+	    time: string;
+	    stream: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = source["time"];
+	        this.stream = source["stream"];
+	        this.text = source["text"];
+	    }
+	}
+	export class OverlayInfo {
+	    name: string;
+	    updatedAt: string;
+	    paths: string[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OverlayInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.updatedAt = source["updatedAt"];
+	        this.paths = source["paths"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ProcessInfo {
+	    pid: number;
+	    parentPid: number;
+	    name: string;
+	    commandLine: string[];
+	    executable?: string;
+	    startedAt?: string;
+	    rssBytes: number;
+	    cpuPercent: number;
+	    connections: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProcessInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pid = source["pid"];
+	        this.parentPid = source["parentPid"];
+	        this.name = source["name"];
+	        this.commandLine = source["commandLine"];
+	        this.executable = source["executable"];
+	        this.startedAt = source["startedAt"];
+	        this.rssBytes = source["rssBytes"];
+	        this.cpuPercent = source["cpuPercent"];
+	        this.connections = source["connections"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ProfileInfo {
+	    name: string;
+	    updatedAt: string;
+	    providers: string[];
+	    proxyUrl?: string;
+	    active: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.updatedAt = source["updatedAt"];
+	        this.providers = source["providers"];
+	        this.proxyUrl = source["proxyUrl"];
+	        this.active = source["active"];
+	        this.error = source["error"];
+	    }
+	}
+	
+	export class ProviderPreset {
+	    id: string;
+	    name: string;
+	    description?: string;
+	    api_base_url: string;
+	    api_key?: string;
+	    api_key_env?: string;
+	    models: string[];
+	    transformer?: ProviderTransformer;
+	    builtin: boolean;
+	    source?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderPreset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.api_base_url = source["api_base_url"];
+	        this.api_key = source["api_key"];
+	        this.api_key_env = source["api_key_env"];
+	        this.models = source["models"];
+	        this.transformer = this.convertValues(source["transformer"], ProviderTransformer);
+	        this.builtin = source["builtin"];
+	        this.source = source["source"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProviderTestResult {
+	    provider: string;
+	    endpoint: string;
+	    protocol: string;
+	    proxy?: string;
+	    dns: CheckStep;
+	    tls: CheckStep;
+	    models: ModelCheckResult[];
+	    ok: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderTestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.endpoint = source["endpoint"];
+	        this.protocol = source["protocol"];
+	        this.proxy = source["proxy"];
+	        this.dns = this.convertValues(source["dns"], CheckStep);
+	        this.tls = this.convertValues(source["tls"], CheckStep);
+	        this.models = this.convertValues(source["models"], ModelCheckResult);
+	        this.ok = source["ok"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class RouteRequest {
+	    model: string;
+	    tokens: number;
+	    thinking: boolean;
+	    tools: string[];
+	    image: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RouteRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.tokens = source["tokens"];
+	        this.thinking = source["thinking"];
+	        this.tools = source["tools"];
+	        this.image = source["image"];
+	    }
+	}
+	export class RouteResolution {
+	    slot: string;
+	    reference: string;
+	    provider: string;
+	    model: string;
+	    resolved: boolean;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RouteResolution(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.slot = source["slot"];
+	        this.reference = source["reference"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.resolved = source["resolved"];
+	        this.message = source["message"];
+	    }
+	}
+	export class RouteSimulation {
+	    slot: string;
+	    reference: string;
+	    provider: string;
+	    model: string;
+	    resolved: boolean;
+	    message?: string;
+	    fallback?: string[];
+	    steps: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RouteSimulation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.slot = source["slot"];
+	        this.reference = source["reference"];
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	        this.resolved = source["resolved"];
+	        this.message = source["message"];
+	        this.fallback = source["fallback"];
+	        this.steps = source["steps"];
+	    }
+	}
+	
+	export class SecretInfo {
+	    name: string;
+	    reference: string;
+	    createdAt: string;
+	    updatedAt: string;
+	    usedBy: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SecretInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.reference = source["reference"];
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	        this.usedBy = source["usedBy"];
+	    }
+	}
+	export class WatchdogStatus {
+	    enabled: boolean;
+	    state: string;
+	    nextRestartAt?: string;
+	    recentCrashes: number;
+	    maxCrashes: number;
+	    windowMinutes: number;
+	    crashes: CrashRecord[];
+	
+	    static createFrom(source: any = {}) {
+	        return new WatchdogStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.state = source["state"];
+	        this.nextRestartAt = source["nextRestartAt"];
+	        this.recentCrashes = source["recentCrashes"];
+	        this.maxCrashes = source["maxCrashes"];
+	        this.windowMinutes = source["windowMinutes"];
+	        this.crashes = this.convertValues(source["crashes"], CrashRecord);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SupervisorStatus {
+	    mode: string;
+	    state: string;
+	    pid?: number;
+	    command?: string[];
+	    startedAt?: string;
+	    exitedAt?: string;
+	    exitCode: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SupervisorStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.state = source["state"];
+	        this.pid = source["pid"];
+	        this.command = source["command"];
+	        this.startedAt = source["startedAt"];
+	        this.exitedAt = source["exitedAt"];
+	        this.exitCode = source["exitCode"];
+	        this.error = source["error"];
 	    }
 	}
 	export class ServiceStatus {
 	    isRunning: boolean;
 	    pid: number;
+	    customRouter?: CustomRouterStatus;
+	    activeProfile?: ActiveProfile;
+	    health: HealthProbe;
+	    process?: ProcessInfo;
+	    supervisor: SupervisorStatus;
+	    watchdog: WatchdogStatus;
 	
 	    static createFrom(source: any = {}) {
 	        return new ServiceStatus(source);
@@ -40,6 +1163,97 @@ export namespace main {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.isRunning = source["isRunning"];
 	        this.pid = source["pid"];
+	        this.customRouter = this.convertValues(source["customRouter"], CustomRouterStatus);
+	        this.activeProfile = this.convertValues(source["activeProfile"], ActiveProfile);
+	        this.health = this.convertValues(source["health"], HealthProbe);
+	        this.process = this.convertValues(source["process"], ProcessInfo);
+	        this.supervisor = this.convertValues(source["supervisor"], SupervisorStatus);
+	        this.watchdog = this.convertValues(source["watchdog"], WatchdogStatus);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TransformerInfo {
+	    name: string;
+	    description: string;
+	    options?: string[];
+	    builtin: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransformerInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.options = source["options"];
+	        this.builtin = source["builtin"];
+	    }
+	}
+	
+	export class TransformerPluginStatus {
+	    path: string;
+	    resolvedPath: string;
+	    options?: Record<string, any>;
+	    name: string;
+	    exists: boolean;
+	    readable: boolean;
+	    modTime?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransformerPluginStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.resolvedPath = source["resolvedPath"];
+	        this.options = source["options"];
+	        this.name = source["name"];
+	        this.exists = source["exists"];
+	        this.readable = source["readable"];
+	        this.modTime = source["modTime"];
+	        this.error = source["error"];
+	    }
+	}
+	
+	
+	
+	export class VaultStatus {
+	    path: string;
+	    exists: boolean;
+	    unlocked: boolean;
+	    secretCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new VaultStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.exists = source["exists"];
+	        this.unlocked = source["unlocked"];
+	        this.secretCount = source["secretCount"];
 	    }
 	}
 
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// healthProbeTimeout bounds each HTTP request of the health probe
+	healthProbeTimeout = 2 * time.Second
+	// ccrRootMessage is what CCR's HTTP server answers on "/"
+	ccrRootMessage = "LLMs API"
+	// maxProbeBodySize bounds how much of a probe response is read
+	maxProbeBodySize = 64 * 1024
+)
+
+// Health states reported by the probe
+const (
+	// HealthStopped means nothing listens on the CCR port
+	HealthStopped = "stopped"
+	// HealthHealthy means CCR answers on its port
+	HealthHealthy = "healthy"
+	// HealthUnresponsive means the port is held, by CCR or an unknown
+	// process, but it does not answer HTTP requests properly
+	HealthUnresponsive = "unresponsive"
+	// HealthForeign means another program holds the CCR port
+	HealthForeign = "foreign"
+	// HealthAuthFailed means CCR answers but rejects the configured APIKEY,
+	// so clients using that key cannot reach it
+	HealthAuthFailed = "authFailed"
+)
+
+// HealthProbe is the result of probing CCR over HTTP
+type HealthProbe struct {
+	State      string `json:"state"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMs  int64  `json:"latencyMs"`
+	// Version is the version CCR reports on "/", if any
+	Version string `json:"version,omitempty"`
+	// StartedAt and UptimeSeconds come from the PID file CCR writes on start
+	StartedAt     string `json:"startedAt,omitempty"`
+	UptimeSeconds int64  `json:"uptimeSeconds,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
+
+// probeResponse is one HTTP response of the probe
+type probeResponse struct {
+	status  int
+	body    map[string]interface{}
+	latency time.Duration
+}
+
+// GetPIDFilePath returns the PID file CCR writes while it runs
+func (a *App) GetPIDFilePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", ".claude-code-router.pid")
+}
+
+// readCCRPIDFile returns the PID recorded by CCR and when it was written
+func (a *App) readCCRPIDFile() (int, time.Time, bool) {
+	pidPath := a.GetPIDFilePath()
+	info, err := os.Stat(pidPath)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	data, err := os.ReadFile(pidPath)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, time.Time{}, false
+	}
+	return pid, info.ModTime(), true
+}
+
+// probeHost returns the address to reach CCR at. CCR binds to 127.0.0.1 when
+// APIKEY is not set, and wildcard addresses are reached over loopback.
+func probeHost(config Config) string {
+	host := strings.Trim(config.HOST, "[]")
+	switch {
+	case host == "" || config.APIKEY == "" || host == "0.0.0.0" || host == "localhost":
+		return "127.0.0.1"
+	case host == "::":
+		return "::1"
+	}
+	return host
+}
+
+// probeGet sends an authenticated GET to CCR and decodes a JSON body if
+// there is one
+func probeGet(ctx context.Context, client *http.Client, url, apiKey string) (probeResponse, error) {
+	var response probeResponse
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return response, err
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("x-api-key", apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return response, err
+	}
+	defer resp.Body.Close()
+	response.latency = time.Since(start)
+	response.status = resp.StatusCode
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	json.Unmarshal(data, &response.body)
+	return response, nil
+}
+
+// probeHealth checks that the process holding the CCR port is CCR and that it
+// answers. pid is the process listening on the port, 0 when none was found.
+// The process counts as CCR when "/" answers like CCR or its PID matches the
+// PID file CCR writes on start.
+func (a *App) probeHealth(config Config, pid int) HealthProbe {
+	probe := HealthProbe{URL: "http://" + net.JoinHostPort(probeHost(config), strconv.Itoa(config.PORT))}
+
+	// 解析环境变量和密钥库引用，密钥库锁定时不带认证
+	apiKey, _ := expandEnvRefs(config.APIKEY)
+	if _, ok := vaultRefName(apiKey); ok {
+		apiKey = ""
+		if resolved, err := a.resolveVaultRefs(config); err == nil {
+			apiKey = resolved.APIKEY
+		}
+	}
+
+	pidFilePID, startedAt, hasPIDFile := a.readCCRPIDFile()
+	ownsPort := hasPIDFile && pid > 0 && pidFilePID == pid
+	if ownsPort {
+		probe.StartedAt = startedAt.Format(time.RFC3339)
+		probe.UptimeSeconds = int64(time.Since(startedAt).Seconds())
+	}
+
+	// 本地探测不经过代理
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}, Timeout: healthProbeTimeout}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*healthProbeTimeout)
+	defer cancel()
+
+	health, err := probeGet(ctx, client, probe.URL+"/health", apiKey)
+	if err != nil {
+		switch {
+		case pid == 0:
+			probe.State = HealthStopped
+		case !ownsPort && hasPIDFile:
+			probe.State = HealthForeign
+			probe.Message = fmt.Sprintf("port %d is held by PID %d, CCR last ran as PID %d", config.PORT, pid, pidFilePID)
+		default:
+			probe.State = HealthUnresponsive
+			probe.Message = redactSecrets(err.Error())
+		}
+		return probe
+	}
+	probe.StatusCode = health.status
+	probe.LatencyMs = health.latency.Milliseconds()
+
+	isCCR := ownsPort
+	if root, err := probeGet(ctx, client, probe.URL+"/", apiKey); err == nil {
+		if message, _ := root.body["message"].(string); message == ccrRootMessage {
+			isCCR = true
+			probe.Version, _ = root.body["version"].(string)
+		}
+	}
+
+	switch {
+	case !isCCR && (health.status == http.StatusUnauthorized || health.status == http.StatusForbidden):
+		probe.State = HealthForeign
+		probe.Message = fmt.Sprintf("port %d rejected the configured APIKEY and could not be identified as claude-code-router", config.PORT)
+	case !isCCR:
+		probe.State = HealthForeign
+		probe.Message = fmt.Sprintf("port %d answers HTTP but not as claude-code-router", config.PORT)
+	case health.status == http.StatusUnauthorized || health.status == http.StatusForbidden:
+		probe.State = HealthAuthFailed
+		probe.Message = fmt.Sprintf("CCR rejected the configured APIKEY with HTTP %d", health.status)
+	case health.status >= 200 && health.status < 300:
+		probe.State = HealthHealthy
+	default:
+		probe.State = HealthUnresponsive
+		probe.Message = fmt.Sprintf("health check returned HTTP %d", health.status)
+	}
+	return probe
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	revisionPrefix = "rev-"
+	// revisionIndexFile keeps the metadata of every revision, so listing
+	// them does not parse each revision file
+	revisionIndexFile = "index.json"
+	// CurrentRevision refers to the config file on disk in DiffConfigRevisions
+	CurrentRevision = "current"
+)
+
+// Kinds of change reported by DiffConfigRevisions
+const (
+	ChangeProviderAdded   = "provider_added"
+	ChangeProviderRemoved = "provider_removed"
+	ChangeProviderChanged = "provider_changed"
+	ChangeModelsChanged   = "models_changed"
+	ChangeRouterChanged   = "router_changed"
+	ChangeSettingChanged  = "setting_changed"
+)
+
+// ConfigRevision describes a recorded state of config.json
+type ConfigRevision struct {
+	ID            string `json:"id"`
+	Timestamp     string `json:"timestamp"`
+	Author        string `json:"author"`
+	Summary       string `json:"summary"`
+	SchemaVersion int    `json:"schemaVersion"`
+}
+
+// revisionRecord is the on-disk form of a revision
+type revisionRecord struct {
+	ConfigRevision
+	Config json.RawMessage `json:"config"`
+}
+
+// ConfigChange is a single semantic difference between two configs
+type ConfigChange struct {
+	Kind    string   `json:"kind"`
+	Path    string   `json:"path"`
+	Before  string   `json:"before,omitempty"`
+	After   string   `json:"after,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// GetHistoryDir returns the directory holding config revisions
+func (a *App) GetHistoryDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "history")
+}
+
+// diffConfigs computes the semantic changes from before to after. Secrets are
+// masked, including key-like values inside transformer options and
+// unmodelled fields.
+func diffConfigs(before, after Config) []ConfigChange {
+	changes := []ConfigChange{}
+
+	setting := func(path, old, new string, secret bool) {
+		if old == new {
+			return
+		}
+		if secret {
+			old, new = maskSecret(old), maskSecret(new)
+		}
+		changes = append(changes, ConfigChange{Kind: ChangeSettingChanged, Path: path, Before: old, After: new})
+	}
+
+	setting("APIKEY", before.APIKEY, after.APIKEY, true)
+	setting("PROXY_URL", before.PROXY_URL, after.PROXY_URL, false)
+	setting("HOST", before.HOST, after.HOST, false)
+	setting("PORT", strconv.Itoa(before.PORT), strconv.Itoa(after.PORT), false)
+	setting("API_TIMEOUT_MS", strconv.Itoa(before.API_TIMEOUT_MS), strconv.Itoa(after.API_TIMEOUT_MS), false)
+	setting("LOG", strconv.FormatBool(before.LOG), strconv.FormatBool(after.LOG), false)
+	setting("NPM_GLOBAL_PREFIX", before.NPM_GLOBAL_PREFIX, after.NPM_GLOBAL_PREFIX, false)
+	setting("CUSTOM_ROUTER_PATH", before.CUSTOM_ROUTER_PATH, after.CUSTOM_ROUTER_PATH, false)
+
+	oldPlugins, _ := json.Marshal(before.Transformers)
+	newPlugins, _ := json.Marshal(after.Transformers)
+	if !bytes.Equal(oldPlugins, newPlugins) {
+		changes = append(changes, ConfigChange{Kind: ChangeSettingChanged, Path: "transformers", Before: string(oldPlugins), After: string(newPlugins)})
+	}
+
+	// 未建模的顶层字段按原始 JSON 比较
+	changes = append(changes, diffExtraFields("", before.fields.extra, after.fields.extra)...)
+
+	oldProviders := make(map[string]Provider)
+	for _, provider := range before.Providers {
+		oldProviders[provider.Name] = provider
+	}
+	newProviders := make(map[string]bool)
+	for _, provider := range after.Providers {
+		newProviders[provider.Name] = true
+		path := "Providers." + provider.Name
+
+		old, ok := oldProviders[provider.Name]
+		if !ok {
+			changes = append(changes, ConfigChange{Kind: ChangeProviderAdded, Path: path, After: provider.Name, Added: provider.Models})
+			continue
+		}
+
+		if old.APIBaseURL != provider.APIBaseURL {
+			changes = append(changes, ConfigChange{Kind: ChangeProviderChanged, Path: path + ".api_base_url", Before: old.APIBaseURL, After: provider.APIBaseURL})
+		}
+		if old.APIKey != provider.APIKey {
+			changes = append(changes, ConfigChange{Kind: ChangeProviderChanged, Path: path + ".api_key", Before: maskSecret(old.APIKey), After: maskSecret(provider.APIKey)})
+		}
+		if added, removed := diffStrings(old.Models, provider.Models); len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, ConfigChange{Kind: ChangeModelsChanged, Path: path + ".models", Added: added, Removed: removed})
+		}
+		oldTransformer, _ := json.Marshal(old.Transformer)
+		newTransformer, _ := json.Marshal(provider.Transformer)
+		if !bytes.Equal(oldTransformer, newTransformer) {
+			changes = append(changes, ConfigChange{Kind: ChangeProviderChanged, Path: path + ".transformer", Before: string(oldTransformer), After: string(newTransformer)})
+		}
+		changes = append(changes, diffExtraFields(path+".", old.fields.extra, provider.fields.extra)...)
+	}
+	for _, provider := range before.Providers {
+		if !newProviders[provider.Name] {
+			changes = append(changes, ConfigChange{Kind: ChangeProviderRemoved, Path: "Providers." + provider.Name, Before: provider.Name, Removed: provider.Models})
+		}
+	}
+
+	slots := after.Router.slotNames()
+	for _, slot := range before.Router.extraSlotNames() {
+		if _, ok := after.Router.Slots[slot]; !ok {
+			slots = append(slots, slot)
+		}
+	}
+	for _, slot := range slots {
+		if old, new := before.Router.slot(slot), after.Router.slot(slot); old != new {
+			changes = append(changes, ConfigChange{Kind: ChangeRouterChanged, Path: "Router." + slot, Before: old, After: new})
+		}
+	}
+	for _, slot := range unionKeys(before.Fallback, after.Fallback) {
+		old, new := strings.Join(before.Fallback[slot], " > "), strings.Join(after.Fallback[slot], " > ")
+		if old != new {
+			changes = append(changes, ConfigChange{Kind: ChangeRouterChanged, Path: "fallback." + slot, Before: old, After: new})
+		}
+	}
+	if before.Router.LongContextThreshold != after.Router.LongContextThreshold {
+		changes = append(changes, ConfigChange{
+			Kind:   ChangeRouterChanged,
+			Path:   "Router.longContextThreshold",
+			Before: strconv.Itoa(before.Router.LongContextThreshold),
+			After:  strconv.Itoa(after.Router.LongContextThreshold),
+		})
+	}
+	changes = append(changes, diffExtraFields("Router.", before.Router.fields.extra, after.Router.fields.extra)...)
+
+	// 原始 JSON 中可能带有密钥，例如 transformer 的 headers 或未建模字段
+	var secrets []string
+	for _, config := range []Config{before, after} {
+		for _, field := range secretFields(config) {
+			if isMaskableSecret(field.Value) {
+				secrets = append(secrets, field.Value)
+			}
+		}
+	}
+	for i := range changes {
+		changes[i].Before = redactKnownSecrets(changes[i].Before, secrets)
+		changes[i].After = redactKnownSecrets(changes[i].After, secrets)
+	}
+
+	return changes
+}
+
+// unionKeys lists the keys of both maps, sorted
+func unionKeys(a, b map[string][]string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string][]string{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffExtraFields compares preserved, unmodelled members by their raw JSON
+func diffExtraFields(prefix string, before, after []rawField) []ConfigChange {
+	var changes []ConfigChange
+	kind := ChangeSettingChanged
+	if prefix == "Router." {
+		kind = ChangeRouterChanged
+	} else if prefix != "" {
+		kind = ChangeProviderChanged
+	}
+
+	compact := func(raw json.RawMessage) string {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return string(raw)
+		}
+		return buf.String()
+	}
+
+	old := make(map[string]string)
+	for _, field := range before {
+		old[field.Key] = compact(field.Value)
+	}
+	seen := make(map[string]bool)
+	for _, field := range after {
+		seen[field.Key] = true
+		value := compact(field.Value)
+		if previous, ok := old[field.Key]; !ok || previous != value {
+			changes = append(changes, ConfigChange{Kind: kind, Path: prefix + field.Key, Before: previous, After: value})
+		}
+	}
+	for _, field := range before {
+		if !seen[field.Key] {
+			changes = append(changes, ConfigChange{Kind: kind, Path: prefix + field.Key, Before: old[field.Key]})
+		}
+	}
+	return changes
+}
+
+// diffStrings returns the items only in after and the items only in before
+func diffStrings(before, after []string) ([]string, []string) {
+	inBefore := make(map[string]bool)
+	for _, item := range before {
+		inBefore[item] = true
+	}
+	inAfter := make(map[string]bool)
+	for _, item := range after {
+		inAfter[item] = true
+	}
+
+	var added, removed []string
+	for _, item := range after {
+		if !inBefore[item] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range before {
+		if !inAfter[item] {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+// summarizeChanges describes a set of changes in one short line
+func summarizeChanges(changes []ConfigChange) string {
+	if len(changes) == 0 {
+		return "no changes"
+	}
+
+	var parts []string
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeProviderAdded:
+			parts = append(parts, "added "+change.Path)
+		case ChangeProviderRemoved:
+			parts = append(parts, "removed "+change.Path)
+		default:
+			parts = append(parts, "changed "+change.Path)
+		}
+	}
+
+	// 摘要只保留前几项，其余以数量表示
+	const maxParts = 3
+	if len(parts) > maxParts {
+		return fmt.Sprintf("%s and %d more", strings.Join(parts[:maxParts], ", "), len(parts)-maxParts)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// recordRevision stores data as a new revision, summarizing how it differs
+// from the latest recorded one. summary overrides the generated summary.
+func (a *App) recordRevision(data []byte, summary string) (ConfigRevision, error) {
+	var revision ConfigRevision
+
+	historyDir := a.GetHistoryDir()
+	if historyDir == "" {
+		return revision, fmt.Errorf("could not determine history path")
+	}
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		return revision, fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return revision, fmt.Errorf("failed to parse config for history: %v", err)
+	}
+
+	if summary == "" {
+		summary = "initial revision"
+		if ids, err := a.revisionIDs(); err == nil && len(ids) > 0 {
+			if previous, err := a.loadRevisionConfig(ids[0]); err == nil {
+				summary = summarizeChanges(diffConfigs(previous, config))
+			}
+		}
+	}
+
+	author, err := os.Hostname()
+	if err != nil {
+		author = "unknown"
+	}
+
+	now := time.Now()
+	stamp := now.Format(backupTimeLayout)
+	id := revisionPrefix + stamp
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(historyDir, id+".json")); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s%s-%d", revisionPrefix, stamp, i)
+	}
+
+	revision = ConfigRevision{
+		ID:            id,
+		Timestamp:     now.Format(time.RFC3339),
+		Author:        author,
+		Summary:       summary,
+		SchemaVersion: ConfigSchemaVersion,
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return revision, err
+	}
+	record, err := json.MarshalIndent(revisionRecord{ConfigRevision: revision, Config: compact.Bytes()}, "", "  ")
+	if err != nil {
+		return revision, err
+	}
+	if err := writeFileAtomic(filepath.Join(historyDir, id+".json"), record, 0600); err != nil {
+		return revision, err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Recorded config revision %s: %s", id, summary)
+	}
+
+	index := a.readRevisionIndex()
+	index[id] = revision
+	a.pruneRevisions(index)
+
+	return revision, nil
+}
+
+// pruneRevisions removes the oldest revisions beyond the configured retention
+// and writes the index of the remaining ones
+func (a *App) pruneRevisions(index map[string]ConfigRevision) {
+	settings, _ := a.GetManagerSettings()
+
+	ids, err := a.revisionIDs()
+	if err != nil {
+		return
+	}
+
+	keep := min(len(ids), settings.HistoryRetention)
+	for _, id := range ids[keep:] {
+		os.Remove(filepath.Join(a.GetHistoryDir(), id+".json"))
+	}
+	a.writeRevisionIndex(ids[:keep], index)
+}
+
+// revisionIDs lists the revisions on disk by file name, newest first
+func (a *App) revisionIDs() ([]string, error) {
+	historyDir := a.GetHistoryDir()
+	if historyDir == "" {
+		return nil, fmt.Errorf("could not determine history path")
+	}
+
+	entries, err := os.ReadDir(historyDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, revisionPrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, ".json"))
+	}
+
+	// ID 包含时间戳，按名称倒序即为由新到旧
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// readRevisionIndex returns the indexed revisions by ID. A missing or
+// unreadable index is treated as empty and rebuilt from the revision files.
+func (a *App) readRevisionIndex() map[string]ConfigRevision {
+	index := make(map[string]ConfigRevision)
+
+	data, err := os.ReadFile(filepath.Join(a.GetHistoryDir(), revisionIndexFile))
+	if err != nil {
+		return index
+	}
+	var revisions []ConfigRevision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: Rebuilding unreadable revision index: %v", err)
+		}
+		return index
+	}
+	for _, revision := range revisions {
+		index[revision.ID] = revision
+	}
+	return index
+}
+
+// writeRevisionIndex writes the index entries of ids, in that order
+func (a *App) writeRevisionIndex(ids []string, index map[string]ConfigRevision) {
+	revisions := make([]ConfigRevision, 0, len(ids))
+	for _, id := range ids {
+		if revision, ok := index[id]; ok {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(a.GetHistoryDir(), revisionIndexFile), data, 0600)
+	}
+	if err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to write revision index: %v", err)
+	}
+}
+
+// readRevision reads a revision record from disk
+func (a *App) readRevision(id string) (revisionRecord, error) {
+	var record revisionRecord
+	if id == "" || id != filepath.Base(id) || !strings.HasPrefix(id, revisionPrefix) {
+		return record, fmt.Errorf("invalid revision id: %q", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(a.GetHistoryDir(), id+".json"))
+	if err != nil {
+		return record, fmt.Errorf("failed to read revision %s: %v", id, err)
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, fmt.Errorf("failed to parse revision %s: %v", id, err)
+	}
+	return record, nil
+}
+
+// loadRevisionConfig returns the config stored in a revision, or the config
+// on disk for CurrentRevision
+func (a *App) loadRevisionConfig(id string) (Config, error) {
+	var config Config
+
+	if id == CurrentRevision {
+		data, err := os.ReadFile(a.GetConfigPath())
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		if err != nil {
+			return config, err
+		}
+		err = json.Unmarshal(data, &config)
+		return config, err
+	}
+
+	record, err := a.readRevision(id)
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(record.Config, &config)
+	return config, err
+}
+
+// ListConfigRevisions lists recorded config revisions, newest first. The
+// metadata comes from the revision index; only revisions missing from it are
+// read from their files.
+func (a *App) ListConfigRevisions() ([]ConfigRevision, error) {
+	revisions := []ConfigRevision{}
+
+	ids, err := a.revisionIDs()
+	if err != nil {
+		return revisions, err
+	}
+
+	index := a.readRevisionIndex()
+	indexed := len(index)
+	stale := false
+	var listed []string
+	for _, id := range ids {
+		revision, ok := index[id]
+		if !ok {
+			// 索引中缺少的修订（索引建立之前的记录）读取文件补齐
+			record, err := a.readRevision(id)
+			if err != nil {
+				if a.logger != nil {
+					a.logger.Printf("WARNING: Skipping unreadable revision %s: %v", id, err)
+				}
+				continue
+			}
+			revision = record.ConfigRevision
+			index[id] = revision
+			stale = true
+		}
+		revisions = append(revisions, revision)
+		listed = append(listed, id)
+	}
+
+	// 补齐了缺少的修订或索引中有已删除的修订时重写索引
+	if stale || indexed != len(listed) {
+		a.writeRevisionIndex(listed, index)
+	}
+	return revisions, nil
+}
+
+// DiffConfigRevisions returns the semantic changes between two revisions.
+// Either ID may be CurrentRevision to compare against the config on disk.
+// Secret values are masked in the result.
+func (a *App) DiffConfigRevisions(from, to string) ([]ConfigChange, error) {
+	before, err := a.loadRevisionConfig(from)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", err)
+		}
+		return nil, err
+	}
+
+	after, err := a.loadRevisionConfig(to)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: %v", err)
+		}
+		return nil, err
+	}
+
+	return diffConfigs(before, after), nil
+}
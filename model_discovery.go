@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// maxModelPages bounds pagination of model listings
+const maxModelPages = 20
+
+// DiscoveredModel is a model served by a provider
+type DiscoveredModel struct {
+	ID            string `json:"id"`
+	DisplayName   string `json:"displayName,omitempty"`
+	OwnedBy       string `json:"ownedBy,omitempty"`
+	Created       string `json:"created,omitempty"`
+	ContextLength int    `json:"contextLength,omitempty"`
+	Configured    bool   `json:"configured"`
+}
+
+// ModelDiscovery lists the models a provider serves and how they compare to
+// the configured models
+type ModelDiscovery struct {
+	Provider string            `json:"provider"`
+	Endpoint string            `json:"endpoint"`
+	Models   []DiscoveredModel `json:"models"`
+	// New lists served models that are not configured
+	New []string `json:"new"`
+	// Missing lists configured models the provider no longer serves
+	Missing []string `json:"missing"`
+}
+
+// modelListEntry covers the model list formats of OpenAI, Anthropic,
+// OpenRouter and Ollama-style servers
+type modelListEntry struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	DisplayName   string          `json:"display_name"`
+	OwnedBy       string          `json:"owned_by"`
+	Created       json.RawMessage `json:"created"`
+	CreatedAt     string          `json:"created_at"`
+	ContextLength int             `json:"context_length"`
+}
+
+// modelListResponse is a page of a model listing
+type modelListResponse struct {
+	Data    []modelListEntry `json:"data"`
+	Models  []modelListEntry `json:"models"`
+	HasMore bool             `json:"has_more"`
+	LastID  string           `json:"last_id"`
+}
+
+// modelsEndpoint derives the model listing URL from a provider's completion
+// endpoint, e.g. .../v1/chat/completions or .../v1/messages becomes .../v1/models
+func modelsEndpoint(apiBaseURL string) (string, error) {
+	parsed, err := url.Parse(apiBaseURL)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("invalid api_base_url %q", apiBaseURL)
+	}
+
+	path := strings.TrimSuffix(parsed.Path, "/")
+	for _, suffix := range []string{"/chat/completions", "/completions", "/messages", "/responses"} {
+		if strings.HasSuffix(path, suffix) {
+			path = strings.TrimSuffix(path, suffix)
+			break
+		}
+	}
+	if !strings.HasSuffix(path, "/models") {
+		path += "/models"
+	}
+
+	parsed.Path = path
+	parsed.RawQuery = ""
+	return parsed.String(), nil
+}
+
+// fetchModels reads every page of a provider's model listing
+func fetchModels(ctx context.Context, client *http.Client, provider Provider, endpoint string) ([]DiscoveredModel, error) {
+	protocol := providerProtocol(provider.APIBaseURL)
+	secrets := []string{provider.APIKey}
+	models := []DiscoveredModel{}
+
+	after := ""
+	for page := 0; page < maxModelPages; page++ {
+		pageURL := endpoint
+		if after != "" {
+			pageURL += "?after_id=" + url.QueryEscape(after)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		setProviderAuth(req, provider, protocol)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list models: %s", redactKnownSecrets(err.Error(), secrets))
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 16*1024*1024))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read model list: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("model list request returned HTTP %d: %s", resp.StatusCode, providerErrorMessage(body, secrets))
+		}
+
+		var list modelListResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("unexpected model list response: %v", err)
+		}
+
+		for _, entry := range append(list.Data, list.Models...) {
+			model := DiscoveredModel{
+				ID:            entry.ID,
+				DisplayName:   entry.DisplayName,
+				OwnedBy:       entry.OwnedBy,
+				Created:       entry.CreatedAt,
+				ContextLength: entry.ContextLength,
+			}
+			if model.ID == "" {
+				model.ID = entry.Name
+			} else if model.DisplayName == "" && entry.Name != entry.ID {
+				model.DisplayName = entry.Name
+			}
+			if model.Created == "" && len(entry.Created) > 0 && string(entry.Created) != "null" {
+				model.Created = strings.Trim(string(entry.Created), `"`)
+			}
+			if model.ID != "" {
+				models = append(models, model)
+			}
+		}
+
+		if !list.HasMore || list.LastID == "" {
+			break
+		}
+		after = list.LastID
+	}
+
+	return models, nil
+}
+
+// compareModels marks which served models are configured and lists the
+// differences in both directions
+func compareModels(discovery *ModelDiscovery, configured []string) {
+	served := make(map[string]bool)
+	want := make(map[string]bool)
+	for _, model := range configured {
+		want[model] = true
+	}
+
+	discovery.New = []string{}
+	discovery.Missing = []string{}
+	for i := range discovery.Models {
+		id := discovery.Models[i].ID
+		served[id] = true
+		discovery.Models[i].Configured = want[id]
+		if !want[id] {
+			discovery.New = append(discovery.New, id)
+		}
+	}
+	for _, model := range configured {
+		if !served[model] {
+			discovery.Missing = append(discovery.Missing, model)
+		}
+	}
+
+	sort.Slice(discovery.Models, func(i, j int) bool {
+		return discovery.Models[i].ID < discovery.Models[j].ID
+	})
+	sort.Strings(discovery.New)
+}
+
+// DiscoverModels lists the models a saved provider serves through its
+// /models endpoint and compares them with the configured models
+func (a *App) DiscoverModels(providerName string) (ModelDiscovery, error) {
+	discovery := ModelDiscovery{Provider: providerName, Models: []DiscoveredModel{}, New: []string{}, Missing: []string{}}
+
+	config, provider, err := a.providerForUse(providerName)
+	if err != nil {
+		return discovery, err
+	}
+
+	discovery.Endpoint, err = modelsEndpoint(provider.APIBaseURL)
+	if err != nil {
+		return discovery, err
+	}
+
+	client, err := newProviderClient(config)
+	if err != nil {
+		return discovery, err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Discovering models of provider %s at %s", providerName, discovery.Endpoint)
+	}
+
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	discovery.Models, err = fetchModels(ctx, client, provider, discovery.Endpoint)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to discover models of provider %s: %v", providerName, err)
+		}
+		discovery.Models = []DiscoveredModel{}
+		return discovery, err
+	}
+
+	compareModels(&discovery, provider.Models)
+
+	if a.logger != nil {
+		a.logger.Printf("Provider %s serves %d models, %d new, %d missing", providerName, len(discovery.Models), len(discovery.New), len(discovery.Missing))
+	}
+
+	return discovery, nil
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OverlayInfo describes a saved overlay
+type OverlayInfo struct {
+	Name      string `json:"name"`
+	UpdatedAt string `json:"updatedAt"`
+	// Paths lists the values the overlay sets
+	Paths []string `json:"paths"`
+	Error string   `json:"error,omitempty"`
+}
+
+// ValueSource names the layer a value of the effective config came from
+type ValueSource struct {
+	Path  string `json:"path"`
+	Layer string `json:"layer"`
+	// Index is the position of the layer, 0 being the base profile
+	Index int `json:"index"`
+}
+
+// LayeredConfig is the effective config of a base profile with overlays
+// applied in order
+type LayeredConfig struct {
+	Config  Config            `json:"config"`
+	Layers  []string          `json:"layers"`
+	Sources []ValueSource     `json:"sources"`
+	Issues  []ValidationIssue `json:"issues"`
+}
+
+// configLayer is the raw content of a base profile or overlay
+type configLayer struct {
+	name string
+	data []byte
+}
+
+// layerSource records which layer set a value
+type layerSource struct {
+	layer string
+	index int
+}
+
+// GetOverlaysDir returns the directory holding config overlays
+func (a *App) GetOverlaysDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "overlays")
+}
+
+// overlayPath validates an overlay name and returns its file path
+func (a *App) overlayPath(name string) (string, error) {
+	if !profileNamePattern.MatchString(name) || strings.TrimSpace(name) != name {
+		return "", fmt.Errorf("invalid overlay name %q: use letters, digits, spaces, '.', '_' and '-'", name)
+	}
+	overlaysDir := a.GetOverlaysDir()
+	if overlaysDir == "" {
+		return "", fmt.Errorf("could not determine overlays path")
+	}
+	return filepath.Join(overlaysDir, name+profileSuffix), nil
+}
+
+func isJSONObject(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+func isJSONArray(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// layerProviderName reads the name of a provider entry, "" if it has none
+func layerProviderName(raw json.RawMessage) string {
+	var provider struct {
+		Name string `json:"name"`
+	}
+	json.Unmarshal(raw, &provider)
+	return provider.Name
+}
+
+// layerProviderPath identifies a provider entry by name, as history does
+func layerProviderPath(raw json.RawMessage, index int) string {
+	if name := layerProviderName(raw); name != "" {
+		return "Providers." + name
+	}
+	return fmt.Sprintf("Providers[%d]", index)
+}
+
+// recordLeaves records source as the origin of every value under raw. Lists
+// count as single values, except Providers whose entries are tracked by name.
+func recordLeaves(sources map[string]layerSource, path string, raw json.RawMessage, source layerSource) {
+	switch {
+	case path == "Providers" && isJSONArray(raw):
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) == nil {
+			for i, item := range items {
+				recordLeaves(sources, layerProviderPath(item, i), item, source)
+			}
+			return
+		}
+	case isJSONObject(raw):
+		fields, err := decodeObject(raw)
+		if err == nil && len(fields) > 0 {
+			for _, field := range fields {
+				recordLeaves(sources, joinPath(path, field.Key), field.Value, source)
+			}
+			return
+		}
+	}
+	sources[path] = source
+}
+
+// clearSources forgets the origin of path and everything below it
+func clearSources(sources map[string]layerSource, path string) {
+	for key := range sources {
+		if path == "" || key == path || strings.HasPrefix(key, path+".") || strings.HasPrefix(key, path+"[") {
+			delete(sources, key)
+		}
+	}
+}
+
+// mergeLayer applies patch to base as a JSON merge patch (RFC 7396): objects
+// are merged key by key, null removes a key and any other value replaces the
+// base value. Providers are the exception: entries are matched by name, so an
+// overlay can change one provider without repeating the others.
+func mergeLayer(base, patch json.RawMessage, path string, source layerSource, sources map[string]layerSource) (json.RawMessage, error) {
+	if path == "Providers" && isJSONArray(base) && isJSONArray(patch) {
+		return mergeProviderLayer(base, patch, source, sources)
+	}
+	if !isJSONObject(patch) || !isJSONObject(base) {
+		if isJSONObject(patch) {
+			// 新增的对象同样按合并补丁处理，去掉其中的 null
+			base = json.RawMessage("{}")
+		} else {
+			clearSources(sources, path)
+			recordLeaves(sources, path, patch, source)
+			return patch, nil
+		}
+		clearSources(sources, path)
+	}
+
+	baseFields, err := decodeObject(base)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	patchFields, err := decodeObject(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for _, field := range patchFields {
+		fieldPath := joinPath(path, field.Key)
+		index := -1
+		for i := range baseFields {
+			if baseFields[i].Key == field.Key {
+				index = i
+				break
+			}
+		}
+
+		if bytes.Equal(bytes.TrimSpace(field.Value), []byte("null")) {
+			if index >= 0 {
+				baseFields = append(baseFields[:index], baseFields[index+1:]...)
+			}
+			clearSources(sources, fieldPath)
+			continue
+		}
+
+		if index < 0 {
+			value, err := mergeLayer(json.RawMessage("null"), field.Value, fieldPath, source, sources)
+			if err != nil {
+				return nil, err
+			}
+			baseFields = append(baseFields, rawField{Key: field.Key, Value: value})
+			continue
+		}
+
+		value, err := mergeLayer(baseFields[index].Value, field.Value, fieldPath, source, sources)
+		if err != nil {
+			return nil, err
+		}
+		baseFields[index].Value = value
+	}
+
+	if len(baseFields) == 0 {
+		sources[path] = source
+	}
+	return encodeObject(baseFields), nil
+}
+
+// mergeProviderLayer patches provider entries matched by name and appends
+// entries for providers the base does not have
+func mergeProviderLayer(base, patch json.RawMessage, source layerSource, sources map[string]layerSource) (json.RawMessage, error) {
+	var baseItems, patchItems []json.RawMessage
+	if err := json.Unmarshal(base, &baseItems); err != nil {
+		return nil, fmt.Errorf("Providers: %v", err)
+	}
+	if err := json.Unmarshal(patch, &patchItems); err != nil {
+		return nil, fmt.Errorf("Providers: %v", err)
+	}
+
+	for i, item := range patchItems {
+		name := layerProviderName(item)
+		index := -1
+		for j := range baseItems {
+			if name != "" && layerProviderName(baseItems[j]) == name {
+				index = j
+				break
+			}
+		}
+
+		if index < 0 {
+			value, err := mergeLayer(json.RawMessage("null"), item, layerProviderPath(item, len(baseItems)), source, sources)
+			if err != nil {
+				return nil, err
+			}
+			baseItems = append(baseItems, value)
+			continue
+		}
+
+		// name 只用于匹配，其来源仍是最初定义该提供商的层
+		path := layerProviderPath(item, i)
+		nameSource, hasName := sources[path+".name"]
+		value, err := mergeLayer(baseItems[index], item, path, source, sources)
+		if err != nil {
+			return nil, err
+		}
+		if hasName {
+			sources[path+".name"] = nameSource
+		}
+		baseItems[index] = value
+	}
+
+	return json.Marshal(baseItems)
+}
+
+// composeLayers merges layers in order, the first one being the base, and
+// returns the effective config together with the origin of every value
+func composeLayers(layers []configLayer) (json.RawMessage, []ValueSource, error) {
+	if len(layers) == 0 {
+		return nil, nil, fmt.Errorf("no base config given")
+	}
+
+	sources := make(map[string]layerSource)
+	effective := json.RawMessage("{}")
+	for i, layer := range layers {
+		if !isJSONObject(layer.data) {
+			return nil, nil, fmt.Errorf("%s is not a JSON object", layer.name)
+		}
+		merged, err := mergeLayer(effective, layer.data, "", layerSource{layer: layer.name, index: i}, sources)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to apply %s: %v", layer.name, err)
+		}
+		effective = merged
+	}
+
+	result := make([]ValueSource, 0, len(sources))
+	for path, source := range sources {
+		if path == "" {
+			continue
+		}
+		result = append(result, ValueSource{Path: path, Layer: source.layer, Index: source.index})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
+	return effective, result, nil
+}
+
+// loadLayers reads a base profile and the named overlays
+func (a *App) loadLayers(base string, overlays []string) ([]configLayer, error) {
+	profilePath, err := a.profilePath(base)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile %q: %v", base, err)
+	}
+	layers := []configLayer{{name: base, data: data}}
+
+	for _, name := range overlays {
+		overlayPath, err := a.overlayPath(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(overlayPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read overlay %q: %v", name, err)
+		}
+		layers = append(layers, configLayer{name: name, data: data})
+	}
+	return layers, nil
+}
+
+// composeConfig builds the effective config of a base profile with overlays
+// and the formatted file content that would be written for CCR
+func (a *App) composeConfig(base string, overlays []string) (LayeredConfig, []byte, error) {
+	result := LayeredConfig{
+		Layers:  append([]string{base}, overlays...),
+		Sources: []ValueSource{},
+		Issues:  []ValidationIssue{},
+	}
+
+	layers, err := a.loadLayers(base, overlays)
+	if err != nil {
+		return result, nil, err
+	}
+
+	effective, sources, err := composeLayers(layers)
+	if err != nil {
+		return result, nil, err
+	}
+	result.Sources = sources
+
+	if err := json.Unmarshal(effective, &result.Config); err != nil {
+		return result, nil, fmt.Errorf("effective config is invalid: %v", err)
+	}
+	result.Issues = a.ValidateConfig(result.Config)
+
+	data, err := json.MarshalIndent(result.Config, "", "  ")
+	if err != nil {
+		return result, nil, err
+	}
+	return result, data, nil
+}
+
+// ComposeConfig shows the effective config of a base profile with overlays
+// applied in order, which layer every value came from and the problems the
+// result has. Nothing is written.
+func (a *App) ComposeConfig(base string, overlays []string) (LayeredConfig, error) {
+	result, _, err := a.composeConfig(base, overlays)
+	if err != nil && a.logger != nil {
+		a.logger.Printf("ERROR: Failed to compose profile %s with overlays %v: %v", base, overlays, err)
+	}
+	result.Config = maskConfigSecrets(result.Config)
+	return result, err
+}
+
+// ActivateLayeredProfile writes the effective config of a base profile with
+// overlays to config.json and records it as active. With restart set, the CCR
+// service is restarted to pick up the new config.
+func (a *App) ActivateLayeredProfile(base string, overlays []string, restart bool) error {
+	result, data, err := a.composeConfig(base, overlays)
+	if err != nil {
+		return err
+	}
+	if hasValidationErrors(result.Issues) {
+		return fmt.Errorf("effective config is invalid: %s", summarizeIssues(result.Issues))
+	}
+
+	if err := a.writeProfileConfig(base, overlays, data); err != nil {
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Activated profile %s with overlays %v", base, overlays)
+	}
+
+	if restart {
+		return a.RestartService()
+	}
+	return nil
+}
+
+// ListOverlays lists the saved overlays and the values each one sets
+func (a *App) ListOverlays() ([]OverlayInfo, error) {
+	overlays := []OverlayInfo{}
+
+	overlaysDir := a.GetOverlaysDir()
+	if overlaysDir == "" {
+		return overlays, fmt.Errorf("could not determine overlays path")
+	}
+
+	entries, err := os.ReadDir(overlaysDir)
+	if os.IsNotExist(err) {
+		return overlays, nil
+	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to list overlays in %s: %v", overlaysDir, err)
+		}
+		return overlays, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, profileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		overlay := OverlayInfo{
+			Name:      strings.TrimSuffix(name, profileSuffix),
+			UpdatedAt: info.ModTime().Format(time.RFC3339),
+			Paths:     []string{},
+		}
+		data, err := os.ReadFile(filepath.Join(overlaysDir, name))
+		switch {
+		case err != nil:
+			overlay.Error = err.Error()
+		case !isJSONObject(data):
+			overlay.Error = "overlay is not a JSON object"
+		default:
+			sources := make(map[string]layerSource)
+			recordLeaves(sources, "", data, layerSource{})
+			for path := range sources {
+				overlay.Paths = append(overlay.Paths, path)
+			}
+			sort.Strings(overlay.Paths)
+		}
+
+		overlays = append(overlays, overlay)
+	}
+
+	sort.Slice(overlays, func(i, j int) bool {
+		return strings.ToLower(overlays[i].Name) < strings.ToLower(overlays[j].Name)
+	})
+
+	return overlays, nil
+}
+
+// overlaySecret is a secret string value in the text of an overlay
+type overlaySecret struct {
+	// provider is the name of the provider the api_key belongs to, "" for
+	// APIKEY
+	provider   string
+	value      string
+	start, end int64
+}
+
+// skipJSONValue consumes the rest of the value that started with tok
+func skipJSONValue(dec *json.Decoder, tok json.Token) error {
+	if delim, ok := tok.(json.Delim); !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// readSecretValue reads the value of a secret key, recording it when it is a
+// string. data is the text dec reads, used to find where the value starts.
+func readSecretValue(data []byte, dec *json.Decoder, provider string) (*overlaySecret, error) {
+	// 键之后只剩空白和冒号，值的起始引号是其后第一个引号
+	after := dec.InputOffset()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := tok.(string)
+	if !ok {
+		return nil, skipJSONValue(dec, tok)
+	}
+	end := dec.InputOffset()
+	start := after + int64(bytes.IndexByte(data[after:end], '"'))
+	return &overlaySecret{provider: provider, value: value, start: start, end: end}, nil
+}
+
+// findOverlaySecrets locates APIKEY and the provider api_keys in the text of
+// an overlay so they can be masked without reformatting it
+func findOverlaySecrets(data []byte) ([]overlaySecret, error) {
+	var secrets []overlaySecret
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("overlay must be a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok {
+		case "APIKEY":
+			secret, err := readSecretValue(data, dec, "")
+			if err != nil {
+				return nil, err
+			}
+			if secret != nil {
+				secrets = append(secrets, *secret)
+			}
+		case "Providers":
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if tok != json.Delim('[') {
+				if err := skipJSONValue(dec, tok); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				if tok != json.Delim('{') {
+					if err := skipJSONValue(dec, tok); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				// name 可能出现在 api_key 之后，读完整个对象再归属
+				var name string
+				var keys []overlaySecret
+				for dec.More() {
+					key, err := dec.Token()
+					if err != nil {
+						return nil, err
+					}
+					switch key {
+					case "api_key":
+						secret, err := readSecretValue(data, dec, "")
+						if err != nil {
+							return nil, err
+						}
+						if secret != nil {
+							keys = append(keys, *secret)
+						}
+					default:
+						tok, err := dec.Token()
+						if err != nil {
+							return nil, err
+						}
+						if key == "name" {
+							name, _ = tok.(string)
+						}
+						if err := skipJSONValue(dec, tok); err != nil {
+							return nil, err
+						}
+					}
+				}
+				if _, err := dec.Token(); err != nil {
+					return nil, err
+				}
+				for _, secret := range keys {
+					secret.provider = name
+					secrets = append(secrets, secret)
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+		default:
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if err := skipJSONValue(dec, tok); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// replaceOverlaySecrets rewrites the secret values of data, replace returns
+// the new value of each secret
+func replaceOverlaySecrets(data []byte, secrets []overlaySecret, replace func(overlaySecret) (string, error)) ([]byte, error) {
+	var out bytes.Buffer
+	last := int64(0)
+	for _, secret := range secrets {
+		value, err := replace(secret)
+		if err != nil {
+			return nil, err
+		}
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(data[last:secret.start])
+		out.Write(quoted)
+		last = secret.end
+	}
+	out.Write(data[last:])
+	return out.Bytes(), nil
+}
+
+// GetOverlay returns the content of an overlay for editing, with literal
+// secrets masked
+func (a *App) GetOverlay(name string) (string, error) {
+	overlayPath, err := a.overlayPath(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(overlayPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read overlay %q: %v", name, err)
+	}
+
+	secrets, err := findOverlaySecrets(data)
+	if err != nil {
+		return "", fmt.Errorf("overlay %q is not valid JSON: %v", name, err)
+	}
+	masked, err := replaceOverlaySecrets(data, secrets, func(secret overlaySecret) (string, error) {
+		if isMaskableSecret(secret.value) {
+			return secretMask, nil
+		}
+		return secret.value, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(masked), nil
+}
+
+// unmaskOverlay puts the values stored in the overlay back into the secrets
+// the editor left masked. Provider keys are matched by provider name.
+func unmaskOverlay(data, stored []byte) ([]byte, error) {
+	secrets, err := findOverlaySecrets(data)
+	if err != nil {
+		return nil, fmt.Errorf("overlay is not valid JSON: %v", err)
+	}
+	storedValues := make(map[string]string)
+	if stored != nil {
+		storedSecrets, _ := findOverlaySecrets(stored)
+		for _, secret := range storedSecrets {
+			storedValues[secret.provider] = secret.value
+		}
+	}
+
+	return replaceOverlaySecrets(data, secrets, func(secret overlaySecret) (string, error) {
+		if secret.value != secretMask {
+			return secret.value, nil
+		}
+		value, ok := storedValues[secret.provider]
+		if !ok || value == "" || value == secretMask {
+			if secret.provider == "" {
+				return "", fmt.Errorf("APIKEY is masked and no stored value was found")
+			}
+			return "", fmt.Errorf("api_key of provider %q is masked and no stored value was found", secret.provider)
+		}
+		return value, nil
+	})
+}
+
+// SaveOverlay creates or replaces an overlay. The content is a partial config
+// whose values must fit the schema; null removes a value from the layers below.
+func (a *App) SaveOverlay(name, content string) error {
+	overlayPath, err := a.overlayPath(name)
+	if err != nil {
+		return err
+	}
+
+	data := []byte(strings.TrimSpace(content))
+	if !isJSONObject(data) {
+		return fmt.Errorf("overlay must be a JSON object")
+	}
+	// 编辑器中的密钥是掩码，还原为叠加层中已保存的值
+	stored, _ := os.ReadFile(overlayPath)
+	data, err = unmaskOverlay(data, stored)
+	if err != nil {
+		return err
+	}
+	var partial Config
+	if err := json.Unmarshal(data, &partial); err != nil {
+		return fmt.Errorf("overlay is not valid JSON: %v", err)
+	}
+	if len(partial.issues) > 0 {
+		issue := partial.issues[0]
+		return fmt.Errorf("overlay does not fit the config schema: %s: %s", issue.Path, issue.Message)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return fmt.Errorf("overlay is not valid JSON: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(overlayPath), 0755); err != nil {
+		return fmt.Errorf("failed to create overlays directory: %v", err)
+	}
+	// 叠加层可能含有密钥，仅允许当前用户读取
+	if err := writeFileAtomic(overlayPath, buf.Bytes(), 0600); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write overlay %s: %v", name, err)
+		}
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Saved overlay %s", name)
+	}
+	return nil
+}
+
+// DeleteOverlay removes a saved overlay
+func (a *App) DeleteOverlay(name string) error {
+	overlayPath, err := a.overlayPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(overlayPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("overlay %q not found", name)
+		}
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Deleted overlay %s", name)
+	}
+	return nil
+}
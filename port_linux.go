@@ -0,0 +1,76 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the LISTEN state in /proc/net/tcp
+const tcpListen = "0A"
+
+// nativeProcessIDByPort finds the process listening on port from /proc: the
+// listening sockets come from /proc/net/tcp and tcp6, their owner from the
+// socket links in /proc/<pid>/fd. It returns 0 when nothing listens.
+func nativeProcessIDByPort(port int) (int, error) {
+	inodes := make(map[string]bool)
+	read := 0
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(table)
+		if err != nil {
+			// 未启用 IPv6 时没有 tcp6
+			continue
+		}
+		read++
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[1:] {
+			// 列依次为 sl local_address rem_address st ... uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[3] != tcpListen {
+				continue
+			}
+			local := fields[1]
+			hexPort := local[strings.LastIndexByte(local, ':')+1:]
+			if p, err := strconv.ParseInt(hexPort, 16, 32); err == nil && int(p) == port && fields[9] != "0" {
+				inodes[fields[9]] = true
+			}
+		}
+	}
+	if read == 0 {
+		return 0, fmt.Errorf("/proc/net/tcp is not readable")
+	}
+	if len(inodes) == 0 {
+		return 0, nil
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list processes: %v", err)
+	}
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			// 无权查看其他用户的进程
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			if inode, ok := strings.CutPrefix(target, "socket:["); ok && inodes[strings.TrimSuffix(inode, "]")] {
+				return pid, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("port %d is in use but its process could not be found, it may belong to another user", port)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package main
+
+// nativeProcessIDByPort is only implemented on Linux, elsewhere lsof or
+// netstat is used
+func nativeProcessIDByPort(port int) (int, error) {
+	return 0, errNativePortLookup
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//go:embed presets/builtin.json
+var builtinPresetsJSON []byte
+
+// ProviderPreset is a template for a provider entry. Built-in presets ship
+// with the manager; user presets are JSON files in the presets directory and
+// replace built-in presets with the same ID.
+type ProviderPreset struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	APIBaseURL  string `json:"api_base_url"`
+	APIKey      string `json:"api_key,omitempty"`
+	// APIKeyEnv names the environment variable the api_key refers to by
+	// default, when the preset has no fixed key
+	APIKeyEnv   string               `json:"api_key_env,omitempty"`
+	Models      []string             `json:"models"`
+	Transformer *ProviderTransformer `json:"transformer,omitempty"`
+	Builtin     bool                 `json:"builtin"`
+	Source      string               `json:"source,omitempty"`
+}
+
+// GetPresetsDir returns the directory holding user provider presets
+func (a *App) GetPresetsDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "presets")
+}
+
+// builtinPresets decodes the presets shipped with the manager
+func builtinPresets() ([]ProviderPreset, error) {
+	var presets []ProviderPreset
+	if err := json.Unmarshal(builtinPresetsJSON, &presets); err != nil {
+		return nil, fmt.Errorf("failed to parse built-in presets: %v", err)
+	}
+	for i := range presets {
+		presets[i].Builtin = true
+	}
+	return presets, nil
+}
+
+// loadUserPresets reads the preset files in the presets directory. Each file
+// holds one preset; its ID defaults to the file name.
+func (a *App) loadUserPresets() []ProviderPreset {
+	var presets []ProviderPreset
+
+	presetsDir := a.GetPresetsDir()
+	entries, err := os.ReadDir(presetsDir)
+	if err != nil {
+		if !os.IsNotExist(err) && a.logger != nil {
+			a.logger.Printf("WARNING: Failed to read presets directory %s: %v", presetsDir, err)
+		}
+		return presets
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(presetsDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if a.logger != nil {
+				a.logger.Printf("WARNING: Failed to read preset %s: %v", path, err)
+			}
+			continue
+		}
+
+		var preset ProviderPreset
+		if err := json.Unmarshal(data, &preset); err != nil {
+			if a.logger != nil {
+				a.logger.Printf("WARNING: Skipping invalid preset %s: %v", path, err)
+			}
+			continue
+		}
+		if preset.ID == "" {
+			preset.ID = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		if preset.Name == "" {
+			preset.Name = preset.ID
+		}
+		preset.Builtin = false
+		preset.Source = path
+		presets = append(presets, preset)
+	}
+
+	return presets
+}
+
+// ListProviderPresets lists the built-in and user provider presets, sorted by
+// name
+func (a *App) ListProviderPresets() ([]ProviderPreset, error) {
+	builtin, err := builtinPresets()
+	if err != nil {
+		return []ProviderPreset{}, err
+	}
+
+	byID := make(map[string]ProviderPreset)
+	for _, preset := range builtin {
+		byID[preset.ID] = preset
+	}
+	for _, preset := range a.loadUserPresets() {
+		byID[preset.ID] = preset
+	}
+
+	presets := make([]ProviderPreset, 0, len(byID))
+	for _, preset := range byID {
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool {
+		return strings.ToLower(presets[i].Name) < strings.ToLower(presets[j].Name)
+	})
+
+	return presets, nil
+}
+
+// CreateProviderFromPreset builds a provider entry from a preset. Non-empty
+// fields of overrides replace the preset values. The provider is returned for
+// the caller to add to the config; nothing is saved.
+func (a *App) CreateProviderFromPreset(presetID string, overrides Provider) (Provider, error) {
+	presets, err := a.ListProviderPresets()
+	if err != nil {
+		return Provider{}, err
+	}
+
+	var preset *ProviderPreset
+	for i := range presets {
+		if presets[i].ID == presetID {
+			preset = &presets[i]
+			break
+		}
+	}
+	if preset == nil {
+		return Provider{}, fmt.Errorf("preset %q not found", presetID)
+	}
+
+	provider := Provider{
+		Name:        preset.ID,
+		APIBaseURL:  preset.APIBaseURL,
+		APIKey:      preset.APIKey,
+		Models:      append([]string{}, preset.Models...),
+		Transformer: preset.Transformer,
+	}
+	if provider.APIKey == "" && preset.APIKeyEnv != "" {
+		provider.APIKey = "${" + preset.APIKeyEnv + "}"
+	}
+
+	if overrides.Name != "" {
+		provider.Name = overrides.Name
+	}
+	if overrides.APIBaseURL != "" {
+		provider.APIBaseURL = overrides.APIBaseURL
+	}
+	if overrides.APIKey != "" {
+		provider.APIKey = overrides.APIKey
+	}
+	if len(overrides.Models) > 0 {
+		provider.Models = append([]string{}, overrides.Models...)
+	}
+	if overrides.Transformer != nil {
+		provider.Transformer = overrides.Transformer
+	}
+
+	if provider.Name == "" || provider.APIBaseURL == "" {
+		return provider, fmt.Errorf("preset %q does not define a name and api_base_url", presetID)
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Created provider %s from preset %s", provider.Name, presetID)
+	}
+
+	return provider, nil
+}
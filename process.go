@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// ProcessInfo describes the process listening on the CCR port
+type ProcessInfo struct {
+	PID         int      `json:"pid"`
+	ParentPID   int      `json:"parentPid"`
+	Name        string   `json:"name"`
+	CommandLine []string `json:"commandLine"`
+	Executable  string   `json:"executable,omitempty"`
+	StartedAt   string   `json:"startedAt,omitempty"`
+	RSSBytes    int64    `json:"rssBytes"`
+	// CPUPercent is the CPU usage since the previous status check, or since
+	// the process started on the first check. 100 is one full core.
+	CPUPercent float64 `json:"cpuPercent"`
+	// Connections counts the established TCP connections of the process
+	Connections int    `json:"connections"`
+	Error       string `json:"error,omitempty"`
+}
+
+// cpuSample remembers the CPU time of the last inspected process so the next
+// status check can report current rather than lifetime usage
+type cpuSample struct {
+	mu      sync.Mutex
+	pid     int
+	cpuTime time.Duration
+	at      time.Time
+}
+
+// cpuPercent returns the CPU usage of pid from its total CPU time, measured
+// against the previous sample when there is one for the same process
+func (s *cpuSample) cpuPercent(pid int, cpuTime time.Duration, startedAt, now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	used, elapsed := cpuTime, now.Sub(startedAt)
+	if s.pid == pid && !s.at.IsZero() && cpuTime >= s.cpuTime {
+		used, elapsed = cpuTime-s.cpuTime, now.Sub(s.at)
+	}
+	s.pid, s.cpuTime, s.at = pid, cpuTime, now
+
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(used) / float64(elapsed) * 100
+}
+
+// processInfo inspects pid. Failures are reported in the Error field so the
+// rest of the service status is still returned.
+func (a *App) processInfo(pid int) *ProcessInfo {
+	info, err := readProcessInfo(pid, &a.cpuSample)
+	info.PID = pid
+	if err != nil {
+		info.Error = err.Error()
+		if a.logger != nil {
+			a.logger.Printf("WARNING: Failed to inspect process %d: %v", pid, err)
+		}
+	}
+	if info.CommandLine == nil {
+		info.CommandLine = []string{}
+	}
+	return &info
+}
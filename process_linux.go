@@ -0,0 +1,139 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc/<pid>/stat. It is 100
+// on every Linux architecture Go supports.
+const clockTicks = 100
+
+// tcpEstablished is the ESTABLISHED state in /proc/net/tcp
+const tcpEstablished = "01"
+
+// readProcessInfo reads the details of pid from /proc
+func readProcessInfo(pid int, sample *cpuSample) (ProcessInfo, error) {
+	info := ProcessInfo{PID: pid}
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(procDir, "stat"))
+	if err != nil {
+		return info, fmt.Errorf("failed to read process status: %v", err)
+	}
+	// 进程名可能包含空格和括号，以最后一个右括号分隔
+	open, end := bytes.IndexByte(stat, '('), bytes.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return info, fmt.Errorf("unexpected format of %s/stat", procDir)
+	}
+	info.Name = string(stat[open+1 : end])
+	fields := strings.Fields(string(stat[end+1:]))
+	// fields[0] 为 stat 的第 3 列（state）
+	if len(fields) < 22 {
+		return info, fmt.Errorf("unexpected format of %s/stat", procDir)
+	}
+	info.ParentPID, _ = strconv.Atoi(fields[1])
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	startTicks, _ := strconv.ParseInt(fields[19], 10, 64)
+	rssPages, _ := strconv.ParseInt(fields[21], 10, 64)
+	info.RSSBytes = rssPages * int64(os.Getpagesize())
+
+	if cmdline, err := os.ReadFile(filepath.Join(procDir, "cmdline")); err == nil {
+		for _, arg := range strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00") {
+			if arg != "" {
+				info.CommandLine = append(info.CommandLine, redactSecrets(arg))
+			}
+		}
+	}
+	// 其他用户的进程无权读取 exe 链接，留空即可
+	info.Executable, _ = os.Readlink(filepath.Join(procDir, "exe"))
+
+	now := time.Now()
+	if bootTime, err := readBootTime(); err == nil {
+		startedAt := bootTime.Add(time.Duration(startTicks) * time.Second / clockTicks)
+		info.StartedAt = startedAt.Format(time.RFC3339)
+		cpuTime := time.Duration(utime+stime) * time.Second / clockTicks
+		info.CPUPercent = sample.cpuPercent(pid, cpuTime, startedAt, now)
+	}
+
+	info.Connections, err = countEstablished(procDir)
+	if err != nil {
+		return info, fmt.Errorf("failed to count connections: %v", err)
+	}
+
+	return info, nil
+}
+
+// readBootTime returns the system boot time from /proc/stat
+func readBootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("btime not found in /proc/stat")
+}
+
+// countEstablished counts the established TCP connections among the sockets
+// the process has open
+func countEstablished(procDir string) (int, error) {
+	fdDir := filepath.Join(procDir, "fd")
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return 0, err
+	}
+
+	inodes := make(map[string]bool)
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if inode, ok := strings.CutPrefix(target, "socket:["); ok {
+			inodes[strings.TrimSuffix(inode, "]")] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, nil
+	}
+
+	count := 0
+	for _, table := range []string{"tcp", "tcp6"} {
+		data, err := os.ReadFile(filepath.Join(procDir, "net", table))
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines[1:] {
+			// 列依次为 sl local_address rem_address st ... uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) >= 10 && fields[3] == tcpEstablished && inodes[fields[9]] {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readProcessInfo is only implemented on Linux, where /proc is available
+func readProcessInfo(pid int, sample *cpuSample) (ProcessInfo, error) {
+	return ProcessInfo{PID: pid}, fmt.Errorf("process details are not available on %s", runtime.GOOS)
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	profileSuffix = ".json"
+	// activeProfileFile records the last activated profile. The leading dot
+	// keeps it apart from profile files.
+	activeProfileFile = ".active.json"
+)
+
+// profileNamePattern restricts profile names to ones that are safe as file
+// names on every platform
+var profileNamePattern = regexp.MustCompile(`^[\p{L}\p{N}_-][\p{L}\p{N} _.-]*$`)
+
+// ProfileInfo describes a saved configuration profile
+type ProfileInfo struct {
+	Name      string   `json:"name"`
+	UpdatedAt string   `json:"updatedAt"`
+	Providers []string `json:"providers"`
+	ProxyURL  string   `json:"proxyUrl,omitempty"`
+	Active    bool     `json:"active"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// ActiveProfile records which profile was last written to config.json
+type ActiveProfile struct {
+	Name        string `json:"name"`
+	ActivatedAt string `json:"activatedAt"`
+	// Overlays lists the overlays applied on top of the profile, in order
+	Overlays []string `json:"overlays,omitempty"`
+	// Hash is the SHA-256 of the config written on activation
+	Hash string `json:"hash"`
+	// Modified is set when config.json no longer matches the profile
+	Modified bool `json:"modified"`
+}
+
+// GetProfilesDir returns the directory holding configuration profiles
+func (a *App) GetProfilesDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "profiles")
+}
+
+// profilePath validates a profile name and returns its file path
+func (a *App) profilePath(name string) (string, error) {
+	if !profileNamePattern.MatchString(name) || strings.TrimSpace(name) != name {
+		return "", fmt.Errorf("invalid profile name %q: use letters, digits, spaces, '.', '_' and '-'", name)
+	}
+	profilesDir := a.GetProfilesDir()
+	if profilesDir == "" {
+		return "", fmt.Errorf("could not determine profiles path")
+	}
+	return filepath.Join(profilesDir, name+profileSuffix), nil
+}
+
+// readActiveProfile loads the active profile record, nil when none is set
+func (a *App) readActiveProfile() *ActiveProfile {
+	data, err := os.ReadFile(filepath.Join(a.GetProfilesDir(), activeProfileFile))
+	if err != nil {
+		return nil
+	}
+	var active ActiveProfile
+	if err := json.Unmarshal(data, &active); err != nil || active.Name == "" {
+		return nil
+	}
+	return &active
+}
+
+// writeActiveProfile records name and its overlays as the profile whose
+// content was written to config.json
+func (a *App) writeActiveProfile(name string, overlays []string, config []byte) error {
+	sum := sha256.Sum256(config)
+	data, err := json.MarshalIndent(ActiveProfile{
+		Name:        name,
+		ActivatedAt: time.Now().Format(time.RFC3339),
+		Overlays:    overlays,
+		Hash:        hex.EncodeToString(sum[:]),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(a.GetProfilesDir(), activeProfileFile), data, 0644)
+}
+
+// activeProfileStatus reports the active profile and whether config.json has
+// changed since it was activated
+func (a *App) activeProfileStatus() *ActiveProfile {
+	active := a.readActiveProfile()
+	if active == nil {
+		return nil
+	}
+	disk, err := a.readConfigSnapshot()
+	active.Modified = err != nil || disk.hash != active.Hash
+	return active
+}
+
+// ListProfiles lists the saved configuration profiles, sorted by name
+func (a *App) ListProfiles() ([]ProfileInfo, error) {
+	profiles := []ProfileInfo{}
+
+	profilesDir := a.GetProfilesDir()
+	if profilesDir == "" {
+		return profiles, fmt.Errorf("could not determine profiles path")
+	}
+
+	entries, err := os.ReadDir(profilesDir)
+	if os.IsNotExist(err) {
+		return profiles, nil
+	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to list profiles in %s: %v", profilesDir, err)
+		}
+		return profiles, err
+	}
+
+	activeName := ""
+	if active := a.readActiveProfile(); active != nil {
+		activeName = active.Name
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, profileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		profile := ProfileInfo{
+			Name:      strings.TrimSuffix(name, profileSuffix),
+			UpdatedAt: info.ModTime().Format(time.RFC3339),
+			Providers: []string{},
+		}
+		profile.Active = profile.Name == activeName
+
+		var config Config
+		data, err := os.ReadFile(filepath.Join(profilesDir, name))
+		if err == nil {
+			err = json.Unmarshal(data, &config)
+		}
+		if err != nil {
+			profile.Error = err.Error()
+		} else {
+			for _, provider := range config.Providers {
+				profile.Providers = append(profile.Providers, provider.Name)
+			}
+			profile.ProxyURL = redactProxyURL(config.PROXY_URL)
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name)
+	})
+
+	return profiles, nil
+}
+
+// SaveAsProfile stores the saved config.json as a named profile and marks it
+// active. An existing profile is only replaced when overwrite is set.
+func (a *App) SaveAsProfile(name string, overwrite bool) error {
+	profilePath, err := a.profilePath(name)
+	if err != nil {
+		return err
+	}
+
+	// 持有配置锁，避免读到服务启动时临时写入的明文密钥
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	data, err := os.ReadFile(a.GetConfigPath())
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to read config for profile %s: %v", name, err)
+		}
+		return fmt.Errorf("failed to read config: %v", err)
+	}
+
+	if _, err := os.Stat(profilePath); err == nil && !overwrite {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(profilePath), 0755); err != nil {
+		return fmt.Errorf("failed to create profiles directory: %v", err)
+	}
+	// 配置中可能含有明文密钥，仅允许当前用户读取
+	if err := writeFileAtomic(profilePath, data, 0600); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write profile %s: %v", name, err)
+		}
+		return err
+	}
+
+	if err := a.writeActiveProfile(name, nil, data); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record active profile: %v", err)
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Saved config as profile %s", name)
+	}
+
+	return nil
+}
+
+// ActivateProfile writes a profile to config.json and records it as active.
+// The current config is backed up first. With restart set, the CCR service is
+// restarted to pick up the new config.
+func (a *App) ActivateProfile(name string, restart bool) error {
+	profilePath, err := a.profilePath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to read profile %s: %v", name, err)
+		}
+		return fmt.Errorf("failed to read profile %q: %v", name, err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("profile %q is not a valid config: %v", name, err)
+	}
+	if issues := a.ValidateConfig(config); hasValidationErrors(issues) {
+		return fmt.Errorf("profile %q is invalid: %s", name, summarizeIssues(issues))
+	}
+
+	if err := a.writeProfileConfig(name, nil, data); err != nil {
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Activated profile %s", name)
+	}
+
+	if restart {
+		return a.RestartService()
+	}
+	return nil
+}
+
+// writeProfileConfig replaces config.json with data on behalf of a profile
+// and its overlays
+func (a *App) writeProfileConfig(name string, overlays []string, data []byte) error {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
+	configPath := a.GetConfigPath()
+	if configPath == "" {
+		return fmt.Errorf("could not determine config path")
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+
+	if _, err := a.backupConfig(); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to back up config before activating profile %s: %v", name, err)
+		}
+		return err
+	}
+
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write profile %s to config: %v", name, err)
+		}
+		return err
+	}
+	// 不更新 loadedConfig：界面仍持有旧配置，由文件监视通知其重新加载，
+	// 避免在界面未重新加载时跳过外部修改检测
+	if err := a.writeActiveProfile(name, overlays, data); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record active profile: %v", err)
+	}
+
+	summary := "activated profile " + name
+	if len(overlays) > 0 {
+		summary += " with overlays " + strings.Join(overlays, ", ")
+	}
+	if _, err := a.recordRevision(data, summary); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to record config revision: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteProfile removes a saved profile. config.json is left untouched.
+func (a *App) DeleteProfile(name string) error {
+	profilePath, err := a.profilePath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(profilePath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		return err
+	}
+
+	if active := a.readActiveProfile(); active != nil && active.Name == name {
+		os.Remove(filepath.Join(a.GetProfilesDir(), activeProfileFile))
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Deleted profile %s", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProtocolOpenAI    = "openai"
+	ProtocolAnthropic = "anthropic"
+
+	// anthropicVersion is sent with requests to Anthropic-compatible endpoints
+	anthropicVersion = "2023-06-01"
+	// maxErrorMessageLength bounds provider error messages in results
+	maxErrorMessageLength = 300
+
+	// modelProbeTimeout bounds a single model check. API_TIMEOUT_MS is meant
+	// for real completions and is far too long for a probe.
+	modelProbeTimeout = 15 * time.Second
+	// providerTestTimeout bounds a whole provider test
+	providerTestTimeout = 45 * time.Second
+	// maxConcurrentProbes limits parallel model checks against one provider
+	maxConcurrentProbes = 4
+)
+
+// Result categories of a model check
+const (
+	CheckOK            = "ok"
+	CheckAuthFailed    = "auth"
+	CheckNotFound      = "not_found"
+	CheckRateLimited   = "rate_limited"
+	CheckRequestError  = "request_error"
+	CheckServerError   = "server_error"
+	CheckNetworkError  = "network"
+	CheckConfigProblem = "config"
+)
+
+// CheckStep reports one phase of a connection, such as DNS or TLS
+type CheckStep struct {
+	OK         bool   `json:"ok"`
+	Skipped    bool   `json:"skipped"`
+	DurationMS int64  `json:"durationMs"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ModelCheckResult reports the outcome of a minimal request for one model
+type ModelCheckResult struct {
+	Model      string `json:"model"`
+	OK         bool   `json:"ok"`
+	Category   string `json:"category"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMS  int64  `json:"latencyMs"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ProviderTestResult reports whether a provider entry actually works
+type ProviderTestResult struct {
+	Provider string             `json:"provider"`
+	Endpoint string             `json:"endpoint"`
+	Protocol string             `json:"protocol"`
+	Proxy    string             `json:"proxy,omitempty"`
+	DNS      CheckStep          `json:"dns"`
+	TLS      CheckStep          `json:"tls"`
+	Models   []ModelCheckResult `json:"models"`
+	OK       bool               `json:"ok"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// providerProtocol guesses the API style of a provider endpoint. CCR points
+// api_base_url at the full request URL, so Anthropic-compatible endpoints end
+// in /messages.
+func providerProtocol(endpoint string) string {
+	if parsed, err := url.Parse(endpoint); err == nil && strings.HasSuffix(strings.TrimSuffix(parsed.Path, "/"), "/messages") {
+		return ProtocolAnthropic
+	}
+	return ProtocolOpenAI
+}
+
+// newProviderClient builds an HTTP client honoring PROXY_URL and API_TIMEOUT_MS
+func newProviderClient(config Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+
+	if config.PROXY_URL != "" {
+		proxyURL, err := url.Parse(config.PROXY_URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROXY_URL: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	timeout := time.Duration(config.API_TIMEOUT_MS) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultAPITimeoutMS * time.Millisecond
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// redactProxyURL hides the password of a proxy URL
+func redactProxyURL(proxy string) string {
+	if parsed, err := url.Parse(proxy); err == nil {
+		return parsed.Redacted()
+	}
+	return redactSecrets(proxy)
+}
+
+// newModelProbe builds the smallest useful completion request for model
+func newModelProbe(ctx context.Context, provider Provider, protocol, model string) (*http.Request, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      model,
+		"max_tokens": 1,
+		"messages":   []map[string]string{{"role": "user", "content": "ping"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.APIBaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setProviderAuth(req, provider, protocol)
+	return req, nil
+}
+
+// setProviderAuth adds the provider's API key in the form its protocol expects
+func setProviderAuth(req *http.Request, provider Provider, protocol string) {
+	if protocol == ProtocolAnthropic {
+		req.Header.Set("x-api-key", provider.APIKey)
+		req.Header.Set("anthropic-version", anthropicVersion)
+	} else if provider.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+provider.APIKey)
+	}
+}
+
+// classifyStatus maps an HTTP status to a check category
+func classifyStatus(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return CheckOK
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return CheckAuthFailed
+	case status == http.StatusNotFound:
+		return CheckNotFound
+	case status == http.StatusTooManyRequests:
+		return CheckRateLimited
+	case status >= 500:
+		return CheckServerError
+	default:
+		return CheckRequestError
+	}
+}
+
+// providerErrorMessage extracts a readable message from an error response
+func providerErrorMessage(body []byte, secrets []string) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &parsed) == nil {
+		var detail struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(parsed.Error, &detail) == nil && detail.Message != "":
+			message = detail.Message
+		case json.Unmarshal(parsed.Error, &text) == nil && text != "":
+			message = text
+		case parsed.Message != "":
+			message = parsed.Message
+		}
+	}
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength] + "..."
+	}
+	return redactKnownSecrets(message, secrets)
+}
+
+// checkModel sends a minimal request for model and records the connection
+// phases into result when trace is set
+func checkModel(ctx context.Context, client *http.Client, provider Provider, protocol, model string, result *ProviderTestResult, trace bool) ModelCheckResult {
+	check := ModelCheckResult{Model: model}
+	secrets := []string{provider.APIKey}
+
+	req, err := newModelProbe(ctx, provider, protocol, model)
+	if err != nil {
+		check.Category = CheckConfigProblem
+		check.Message = redactKnownSecrets(err.Error(), secrets)
+		return check
+	}
+
+	if trace {
+		var dnsStart, tlsStart time.Time
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+			DNSDone: func(info httptrace.DNSDoneInfo) {
+				result.DNS = CheckStep{OK: info.Err == nil, DurationMS: time.Since(dnsStart).Milliseconds()}
+				if info.Err != nil {
+					result.DNS.Error = info.Err.Error()
+				} else {
+					var addrs []string
+					for _, addr := range info.Addrs {
+						addrs = append(addrs, addr.String())
+					}
+					result.DNS.Detail = strings.Join(addrs, ", ")
+				}
+			},
+			TLSHandshakeStart: func() { tlsStart = time.Now() },
+			TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+				result.TLS = CheckStep{OK: err == nil, DurationMS: time.Since(tlsStart).Milliseconds()}
+				if err != nil {
+					result.TLS.Error = err.Error()
+				} else {
+					result.TLS.Detail = tls.VersionName(state.Version)
+				}
+			},
+		}))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	check.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Category = CheckNetworkError
+		check.Message = redactKnownSecrets(err.Error(), secrets)
+		return check
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	check.StatusCode = resp.StatusCode
+	check.Category = classifyStatus(resp.StatusCode)
+	check.OK = check.Category == CheckOK
+	if !check.OK {
+		check.Message = providerErrorMessage(body, secrets)
+	}
+	return check
+}
+
+// checkProvider tests every model of provider using client, several at a
+// time, each bounded by modelProbeTimeout. DNS and TLS are reported from the
+// first model's request; with a proxy, name resolution happens on the proxy
+// and is reported as skipped.
+func checkProvider(ctx context.Context, client *http.Client, provider Provider, proxy string) ProviderTestResult {
+	result := ProviderTestResult{
+		Provider: provider.Name,
+		Endpoint: provider.APIBaseURL,
+		Protocol: providerProtocol(provider.APIBaseURL),
+		Proxy:    redactProxyURL(proxy),
+		Models:   []ModelCheckResult{},
+	}
+
+	endpoint, err := url.Parse(provider.APIBaseURL)
+	if err != nil || endpoint.Host == "" {
+		result.Error = fmt.Sprintf("invalid api_base_url %q", provider.APIBaseURL)
+		return result
+	}
+	if len(provider.Models) == 0 {
+		result.Error = "provider has no models to test"
+		return result
+	}
+
+	// 未经历的阶段（连接复用、代理解析、明文 HTTP）标记为跳过
+	result.DNS = CheckStep{Skipped: true}
+	result.TLS = CheckStep{Skipped: true}
+	if endpoint.Scheme == "http" {
+		result.TLS.Detail = "plain HTTP"
+	}
+
+	// 并发检查各模型，结果按配置中的顺序排列；仅第一个请求记录 DNS 与 TLS
+	result.Models = make([]ModelCheckResult, len(provider.Models))
+	slots := make(chan struct{}, maxConcurrentProbes)
+	var wg sync.WaitGroup
+	for i, model := range provider.Models {
+		wg.Add(1)
+		go func(i int, model string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			probeCtx, cancel := context.WithTimeout(ctx, modelProbeTimeout)
+			defer cancel()
+			result.Models[i] = checkModel(probeCtx, client, provider, result.Protocol, model, &result, i == 0)
+		}(i, model)
+	}
+	wg.Wait()
+
+	result.OK = true
+	for _, check := range result.Models {
+		if !check.OK {
+			result.OK = false
+		}
+	}
+	if proxy != "" {
+		result.DNS = CheckStep{Skipped: true, Detail: "resolved by proxy"}
+	}
+
+	return result
+}
+
+// providerForUse finds a provider in the saved config and resolves its
+// environment and vault references
+func (a *App) providerForUse(name string) (Config, Provider, error) {
+	config, _, err := a.readConfig()
+	if err != nil {
+		return config, Provider{}, err
+	}
+
+	config, err = a.resolveVaultRefs(expandConfigEnv(config))
+	if err != nil {
+		return config, Provider{}, err
+	}
+
+	for _, provider := range config.Providers {
+		if provider.Name == name {
+			return config, provider, nil
+		}
+	}
+	return config, Provider{}, fmt.Errorf("provider %q not found in saved config", name)
+}
+
+// TestProvider sends a minimal request for each model of a saved provider and
+// reports DNS, TLS, authentication, latency and HTTP status results
+func (a *App) TestProvider(name string) (ProviderTestResult, error) {
+	config, provider, err := a.providerForUse(name)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to test provider %s: %v", name, err)
+		}
+		return ProviderTestResult{Provider: name, Models: []ModelCheckResult{}}, err
+	}
+
+	client, err := newProviderClient(config)
+	if err != nil {
+		return ProviderTestResult{Provider: name, Models: []ModelCheckResult{}}, err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Testing provider %s at %s", name, provider.APIBaseURL)
+	}
+
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, providerTestTimeout)
+	defer cancel()
+	result := checkProvider(ctx, client, provider, config.PROXY_URL)
+
+	if a.logger != nil {
+		a.logger.Printf("Provider %s test finished, ok: %v", name, result.OK)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// probeModel returns the model named in a probe request
+func probeModel(t *testing.T, r *http.Request) string {
+	t.Helper()
+	var body struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("probe body is not JSON: %v", err)
+	}
+	return body.Model
+}
+
+func TestCheckProviderOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test-openai-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch probeModel(t, r) {
+		case "gpt-ok":
+			w.Write([]byte(`{"choices":[]}`))
+		case "gpt-limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"slow down"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"model not found"}}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := Provider{
+		Name:       "openai",
+		APIBaseURL: server.URL + "/v1/chat/completions",
+		APIKey:     "sk-test-openai-key",
+		Models:     []string{"gpt-ok", "gpt-missing", "gpt-limited"},
+	}
+	result := checkProvider(context.Background(), server.Client(), provider, "")
+
+	if result.Protocol != ProtocolOpenAI {
+		t.Errorf("protocol = %q, want %q", result.Protocol, ProtocolOpenAI)
+	}
+	if result.OK {
+		t.Errorf("result is OK although a model failed")
+	}
+	if !result.TLS.Skipped || result.TLS.Detail != "plain HTTP" {
+		t.Errorf("TLS = %+v, want skipped plain HTTP", result.TLS)
+	}
+
+	want := []struct {
+		model    string
+		category string
+		status   int
+		message  string
+	}{
+		{"gpt-ok", CheckOK, http.StatusOK, ""},
+		{"gpt-missing", CheckNotFound, http.StatusNotFound, "model not found"},
+		{"gpt-limited", CheckRateLimited, http.StatusTooManyRequests, "slow down"},
+	}
+	if len(result.Models) != len(want) {
+		t.Fatalf("got %d model results, want %d", len(result.Models), len(want))
+	}
+	for i, w := range want {
+		got := result.Models[i]
+		if got.Model != w.model || got.Category != w.category || got.StatusCode != w.status || got.Message != w.message {
+			t.Errorf("model %d = %+v, want %s %s %d %q", i, got, w.model, w.category, w.status, w.message)
+		}
+	}
+}
+
+func TestCheckProviderAnthropic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("probe sent to %s", r.URL.Path)
+		}
+		if r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("anthropic-version = %q", r.Header.Get("anthropic-version"))
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Authorization header sent to an Anthropic endpoint")
+		}
+		// 错误信息中回显密钥，检查结果中不应出现
+		key := r.Header.Get("x-api-key")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key ` + key + `"}}`))
+	}))
+	defer server.Close()
+
+	provider := Provider{
+		Name:       "anthropic",
+		APIBaseURL: server.URL + "/v1/messages",
+		APIKey:     "wrong-anthropic-key",
+		Models:     []string{"claude-sonnet-4"},
+	}
+	result := checkProvider(context.Background(), server.Client(), provider, "")
+
+	if result.Protocol != ProtocolAnthropic {
+		t.Errorf("protocol = %q, want %q", result.Protocol, ProtocolAnthropic)
+	}
+	if result.OK || len(result.Models) != 1 {
+		t.Fatalf("result = %+v, want one failed model", result)
+	}
+	check := result.Models[0]
+	if check.Category != CheckAuthFailed || check.StatusCode != http.StatusUnauthorized {
+		t.Errorf("check = %+v, want auth failure", check)
+	}
+	if strings.Contains(check.Message, provider.APIKey) {
+		t.Errorf("message leaks the API key: %q", check.Message)
+	}
+}
+
+func TestCheckProviderConcurrent(t *testing.T) {
+	models := []string{"a", "b", "c"}
+
+	// 所有请求同时到达时才返回成功，逐个发送会超时失败
+	var mu sync.Mutex
+	arrived := 0
+	all := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		arrived++
+		if arrived == len(models) {
+			close(all)
+		}
+		mu.Unlock()
+
+		select {
+		case <-all:
+			w.Write([]byte(`{}`))
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	provider := Provider{Name: "p", APIBaseURL: server.URL + "/v1/chat/completions", Models: models}
+	result := checkProvider(context.Background(), server.Client(), provider, "")
+	if !result.OK {
+		t.Errorf("models were not probed concurrently: %+v", result.Models)
+	}
+}
+
+func TestCheckProviderDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	provider := Provider{Name: "p", APIBaseURL: server.URL + "/v1/chat/completions", Models: []string{"slow"}}
+	start := time.Now()
+	result := checkProvider(ctx, server.Client(), provider, "")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("check took %v, the deadline was not honored", elapsed)
+	}
+	if result.OK || result.Models[0].Category != CheckNetworkError {
+		t.Errorf("check = %+v, want a network error", result.Models[0])
+	}
+}
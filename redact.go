@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretMask replaces secret values shown outside the backend
+const secretMask = "********"
+
+// minKnownSecretLength avoids masking short values that would match ordinary
+// words in logs
+const minKnownSecretLength = 8
+
+// secretPatterns match key-like text in logs and messages. Each pattern keeps
+// the groups around the secret and replaces the secret itself.
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// JSON members such as "api_key": "..." or "Authorization": "..."
+	{regexp.MustCompile(`(?i)("(?:api_?key|apikey|x-api-key|authorization|token|access_token|secret|password)"\s*:\s*")[^"]*(")`), "${1}" + secretMask + "${2}"},
+	// Bearer and Basic credentials
+	{regexp.MustCompile(`(?i)\b(Bearer|Basic)\s+[A-Za-z0-9._~+/=-]{8,}`), "${1} " + secretMask},
+	// Header and key=value forms such as x-api-key: ... or api_key=...
+	{regexp.MustCompile(`(?i)\b(x-api-key|x-goog-api-key|api[_-]?key)(\s*[:=]\s*)[^\s,;&"']+`), "${1}${2}" + secretMask},
+	// Query string keys
+	{regexp.MustCompile(`(?i)([?&](?:key|token|access_token)=)[^&\s"']+`), "${1}" + secretMask},
+	// OpenAI style keys (sk-..., sk-ant-..., sk-or-...)
+	{regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{8,}`), secretMask},
+}
+
+// maskSecret hides a secret value while still showing whether it is set
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return secretMask
+}
+
+// redactSecrets masks key-like patterns in text
+func redactSecrets(text string) string {
+	for _, p := range secretPatterns {
+		text = p.pattern.ReplaceAllString(text, p.replacement)
+	}
+	return text
+}
+
+// redactKnownSecrets masks key-like patterns and every occurrence of the given
+// secret values in text
+func redactKnownSecrets(text string, secrets []string) string {
+	// 先替换较长的值，避免部分替换
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	for _, secret := range secrets {
+		if len(secret) >= minKnownSecretLength {
+			text = strings.ReplaceAll(text, secret, secretMask)
+		}
+	}
+	return redactSecrets(text)
+}
+
+// redactingWriter masks key-like patterns before writing, so secrets never
+// reach config-manager.log
+type redactingWriter struct {
+	w io.Writer
+}
+
+// Write implements io.Writer
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write([]byte(redactSecrets(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// isMaskableSecret reports whether a secret field holds a literal secret, as
+// opposed to an environment or vault reference that is safe to show
+func isMaskableSecret(value string) bool {
+	if value == "" {
+		return false
+	}
+	if _, ok := vaultRefName(value); ok {
+		return false
+	}
+	return len(envRefNames(value)) == 0
+}
+
+// maskConfigSecrets returns a copy of config with literal secrets masked
+func maskConfigSecrets(config Config) Config {
+	if isMaskableSecret(config.APIKEY) {
+		config.APIKEY = secretMask
+	}
+	providers := make([]Provider, len(config.Providers))
+	copy(providers, config.Providers)
+	for i := range providers {
+		if isMaskableSecret(providers[i].APIKey) {
+			providers[i].APIKey = secretMask
+		}
+	}
+	config.Providers = providers
+	return config
+}
+
+// unmaskConfigSecrets puts the stored values back into secret fields the UI
+// left masked. Providers are matched by the name they were loaded with, so a
+// renamed provider keeps its key, while a new provider whose key is still
+// masked is rejected rather than given the key of another provider.
+// SavedName is cleared on every provider.
+func unmaskConfigSecrets(config, stored Config) (Config, error) {
+	if config.APIKEY == secretMask {
+		if stored.APIKEY == "" {
+			return config, fmt.Errorf("APIKEY is masked and no stored value was found")
+		}
+		config.APIKEY = stored.APIKEY
+	}
+
+	storedKeys := make(map[string]string)
+	for _, provider := range stored.Providers {
+		storedKeys[provider.Name] = provider.APIKey
+	}
+
+	providers := make([]Provider, len(config.Providers))
+	copy(providers, config.Providers)
+	for i := range providers {
+		savedName := providers[i].SavedName
+		if savedName == "" {
+			savedName = providers[i].Name
+		}
+		providers[i].SavedName = ""
+		if providers[i].APIKey != secretMask {
+			continue
+		}
+		value := storedKeys[savedName]
+		if value == "" {
+			return config, fmt.Errorf("Providers[%d].api_key is masked and no stored value was found for provider %q; enter its API key again", i, savedName)
+		}
+		providers[i].APIKey = value
+	}
+	config.Providers = providers
+
+	return config, nil
+}
+
+// storedConfigForUnmask returns the config whose secrets the UI was shown
+// masked: the loaded snapshot, or the file on disk. The caller must hold
+// configMu.
+func (a *App) storedConfigForUnmask() Config {
+	var stored Config
+	data := []byte(nil)
+	if a.loadedConfig != nil {
+		data = a.loadedConfig.data
+	} else if disk, err := a.readConfigSnapshot(); err == nil {
+		data = disk.data
+	}
+	if data != nil {
+		json.Unmarshal(data, &stored)
+	}
+	return stored
+}
+
+// knownSecretValues lists the literal secrets in config.json and, while the
+// vault is unlocked, the vault secrets
+func (a *App) knownSecretValues() []string {
+	var secrets []string
+
+	if data, err := os.ReadFile(a.GetConfigPath()); err == nil {
+		var config Config
+		if json.Unmarshal(data, &config) == nil {
+			for _, field := range secretFields(expandConfigEnv(config)) {
+				if isMaskableSecret(field.Value) {
+					secrets = append(secrets, field.Value)
+				}
+			}
+		}
+	}
+
+	a.vaultMu.Lock()
+	if a.vault != nil {
+		for _, entry := range a.vault.entries {
+			secrets = append(secrets, entry.Value)
+		}
+	}
+	a.vaultMu.Unlock()
+
+	return secrets
+}
+
+// RevealConfigSecret returns the stored API key of a provider, or the global
+// APIKEY when providerName is empty. Every reveal is logged.
+func (a *App) RevealConfigSecret(providerName string) (string, error) {
+	config, _, err := a.readConfig()
+	if err != nil {
+		return "", err
+	}
+
+	field := "APIKEY"
+	value := config.APIKEY
+	if providerName != "" {
+		found := false
+		for _, provider := range config.Providers {
+			if provider.Name == providerName {
+				field = fmt.Sprintf("api_key of provider %s", providerName)
+				value = provider.APIKey
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("provider %q not found in saved config", providerName)
+		}
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Revealed %s", field)
+	}
+	return value, nil
+}
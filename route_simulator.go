@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// backgroundModelPrefix marks the small model Claude Code uses for background
+// tasks; CCR sends those requests to the background slot
+const backgroundModelPrefix = "claude-3-5-haiku"
+
+// webSearchToolPrefix marks the server-side web search tool
+const webSearchToolPrefix = "web_search"
+
+// RouteRequest is the shape of a request as far as routing is concerned
+type RouteRequest struct {
+	// Model is the model Claude Code asked for, or an explicit "provider,model"
+	Model string `json:"model"`
+	// Tokens estimates the prompt size in tokens
+	Tokens   int  `json:"tokens"`
+	Thinking bool `json:"thinking"`
+	// Tools lists tool types or names, such as web_search_20250305
+	Tools []string `json:"tools"`
+	// Image reports whether the messages carry image content
+	Image bool `json:"image"`
+}
+
+// RouteSimulation explains where a request would be routed
+type RouteSimulation struct {
+	// Slot is the Router slot chosen, empty when the request names a
+	// "provider,model" itself
+	Slot      string `json:"slot"`
+	Reference string `json:"reference"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Resolved  bool   `json:"resolved"`
+	Message   string `json:"message,omitempty"`
+	// Fallback lists the routes CCR tries in order if the chosen one fails
+	Fallback []string `json:"fallback,omitempty"`
+	// Steps explains each routing rule in the order CCR applies them
+	Steps []string `json:"steps"`
+}
+
+// usesWebSearch reports whether any tool is the web search tool
+func usesWebSearch(tools []string) bool {
+	for _, tool := range tools {
+		if strings.HasPrefix(strings.TrimSpace(tool), webSearchToolPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// simulateRoute applies CCR's routing rules to request: an explicit
+// "provider,model", then long context, background, web search, image content,
+// thinking and finally the default slot. The first rule that matches a configured slot wins.
+func simulateRoute(config Config, request RouteRequest) RouteSimulation {
+	simulation := RouteSimulation{Steps: []string{}}
+	step := func(format string, args ...interface{}) {
+		simulation.Steps = append(simulation.Steps, fmt.Sprintf(format, args...))
+	}
+	choose := func(slot, reference string) RouteSimulation {
+		resolution := resolveRouteRef(config.Providers, slot, reference)
+		simulation.Slot = slot
+		simulation.Reference = reference
+		simulation.Provider = resolution.Provider
+		simulation.Model = resolution.Model
+		simulation.Resolved = resolution.Resolved
+		simulation.Message = resolution.Message
+		if chain := config.Fallback[slot]; len(chain) > 0 {
+			simulation.Fallback = chain
+			step("if %s fails, CCR tries %s in order", reference, strings.Join(chain, ", "))
+		}
+		return simulation
+	}
+	// rule 记录一条规则的判断结果，条件满足且槽位已配置时返回 true
+	rule := func(slot string, matched bool, reason string) bool {
+		reference := config.Router.slot(slot)
+		switch {
+		case !matched:
+			step("%s: skipped, %s", slot, reason)
+			return false
+		case reference == "":
+			step("%s: %s, but the slot is not configured", slot, reason)
+			return false
+		default:
+			step("%s: %s, routing to %s", slot, reason, reference)
+			return true
+		}
+	}
+
+	if config.CUSTOM_ROUTER_PATH != "" {
+		step("custom router %s runs first and may override the result below", config.CUSTOM_ROUTER_PATH)
+	}
+
+	if strings.Contains(request.Model, ",") {
+		step("model %q names a provider and model explicitly, the Router is bypassed", request.Model)
+		return choose("", request.Model)
+	}
+
+	threshold := config.Router.LongContextThreshold
+	if threshold <= 0 {
+		threshold = defaultLongContextThreshold
+	}
+	longContext := request.Tokens > threshold
+	reason := fmt.Sprintf("%d tokens exceed the threshold of %d", request.Tokens, threshold)
+	if !longContext {
+		reason = fmt.Sprintf("%d tokens are within the threshold of %d", request.Tokens, threshold)
+	}
+	if rule("longContext", longContext, reason) {
+		return choose("longContext", config.Router.LongContext)
+	}
+
+	background := strings.HasPrefix(request.Model, backgroundModelPrefix)
+	reason = fmt.Sprintf("model %q is a %s model", request.Model, backgroundModelPrefix)
+	if !background {
+		reason = fmt.Sprintf("model %q is not a %s model", request.Model, backgroundModelPrefix)
+	}
+	if rule("background", background, reason) {
+		return choose("background", config.Router.Background)
+	}
+
+	webSearch := usesWebSearch(request.Tools)
+	reason = "the request has a web search tool"
+	if !webSearch {
+		reason = "the request has no web search tool"
+	}
+	if rule("webSearch", webSearch, reason) {
+		return choose("webSearch", config.Router.WebSearch)
+	}
+
+	reason = "the request has image content"
+	if !request.Image {
+		reason = "the request has no image content"
+	}
+	if rule("image", request.Image, reason) {
+		return choose("image", config.Router.Image)
+	}
+
+	reason = "thinking is enabled"
+	if !request.Thinking {
+		reason = "thinking is not enabled"
+	}
+	if rule("think", request.Thinking, reason) {
+		return choose("think", config.Router.Think)
+	}
+
+	if config.Router.Default == "" {
+		step("default: the slot is not configured, CCR cannot route the request")
+		simulation.Slot = "default"
+		simulation.Message = "no default route is set"
+		return simulation
+	}
+	step("default: no other rule applied, routing to %s", config.Router.Default)
+	return choose("default", config.Router.Default)
+}
+
+// SimulateRoute explains which Router slot, provider and model CCR would pick
+// for a request under config, without sending anything
+func (a *App) SimulateRoute(config Config, request RouteRequest) RouteSimulation {
+	return simulateRoute(config, request)
+}
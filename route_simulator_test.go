@@ -0,0 +1,205 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func simulatorConfig() Config {
+	return Config{
+		Providers: []Provider{
+			{Name: "deepseek", Models: []string{"deepseek-chat", "deepseek-reasoner"}},
+			{Name: "gemini", Models: []string{"gemini-2.5-pro", "gemini-2.5-flash"}},
+			{Name: "ollama", Models: []string{"qwen2.5-coder:latest"}},
+		},
+		Router: Router{
+			Default:     "deepseek,deepseek-chat",
+			Background:  "ollama,qwen2.5-coder:latest",
+			Think:       "deepseek,deepseek-reasoner",
+			LongContext: "gemini,gemini-2.5-pro",
+			WebSearch:   "gemini,gemini-2.5-flash",
+		},
+	}
+}
+
+func TestSimulateRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   func(*Config)
+		request  RouteRequest
+		slot     string
+		provider string
+		model    string
+		resolved bool
+	}{
+		{
+			name:     "default",
+			request:  RouteRequest{Model: "claude-sonnet-4", Tokens: 1000},
+			slot:     "default",
+			provider: "deepseek",
+			model:    "deepseek-chat",
+			resolved: true,
+		},
+		{
+			name:     "explicit provider and model",
+			request:  RouteRequest{Model: "gemini,gemini-2.5-flash", Tokens: 100000, Thinking: true},
+			slot:     "",
+			provider: "gemini",
+			model:    "gemini-2.5-flash",
+			resolved: true,
+		},
+		{
+			name:     "long context over default threshold",
+			request:  RouteRequest{Model: "claude-sonnet-4", Tokens: 60001},
+			slot:     "longContext",
+			provider: "gemini",
+			model:    "gemini-2.5-pro",
+			resolved: true,
+		},
+		{
+			name:     "at default threshold is not long context",
+			request:  RouteRequest{Model: "claude-sonnet-4", Tokens: 60000},
+			slot:     "default",
+			provider: "deepseek",
+			model:    "deepseek-chat",
+			resolved: true,
+		},
+		{
+			name:     "custom threshold",
+			config:   func(c *Config) { c.Router.LongContextThreshold = 20000 },
+			request:  RouteRequest{Model: "claude-sonnet-4", Tokens: 30000},
+			slot:     "longContext",
+			provider: "gemini",
+			model:    "gemini-2.5-pro",
+			resolved: true,
+		},
+		{
+			name:     "long context wins over thinking",
+			request:  RouteRequest{Model: "claude-sonnet-4", Tokens: 80000, Thinking: true},
+			slot:     "longContext",
+			provider: "gemini",
+			model:    "gemini-2.5-pro",
+			resolved: true,
+		},
+		{
+			name:     "long context slot not configured",
+			config:   func(c *Config) { c.Router.LongContext = "" },
+			request:  RouteRequest{Model: "claude-sonnet-4", Tokens: 80000},
+			slot:     "default",
+			provider: "deepseek",
+			model:    "deepseek-chat",
+			resolved: true,
+		},
+		{
+			name:     "background model",
+			request:  RouteRequest{Model: "claude-3-5-haiku-20241022", Tokens: 500, Thinking: true},
+			slot:     "background",
+			provider: "ollama",
+			model:    "qwen2.5-coder:latest",
+			resolved: true,
+		},
+		{
+			name:     "web search tool",
+			request:  RouteRequest{Model: "claude-sonnet-4", Tools: []string{"Bash", "web_search_20250305"}, Thinking: true},
+			slot:     "webSearch",
+			provider: "gemini",
+			model:    "gemini-2.5-flash",
+			resolved: true,
+		},
+		{
+			name:     "image content",
+			config:   func(c *Config) { c.Router.Image = "gemini,gemini-2.5-pro" },
+			request:  RouteRequest{Model: "claude-sonnet-4", Image: true, Thinking: true},
+			slot:     "image",
+			provider: "gemini",
+			model:    "gemini-2.5-pro",
+			resolved: true,
+		},
+		{
+			name:     "image slot not configured",
+			request:  RouteRequest{Model: "claude-sonnet-4", Image: true},
+			slot:     "default",
+			provider: "deepseek",
+			model:    "deepseek-chat",
+			resolved: true,
+		},
+		{
+			name:     "thinking",
+			request:  RouteRequest{Model: "claude-sonnet-4", Thinking: true, Tools: []string{"Bash"}},
+			slot:     "think",
+			provider: "deepseek",
+			model:    "deepseek-reasoner",
+			resolved: true,
+		},
+		{
+			name:     "unresolved reference",
+			config:   func(c *Config) { c.Router.Think = "deepseek,deepseek-r1" },
+			request:  RouteRequest{Model: "claude-sonnet-4", Thinking: true},
+			slot:     "think",
+			provider: "deepseek",
+			model:    "deepseek-r1",
+			resolved: false,
+		},
+		{
+			name:     "no default",
+			config:   func(c *Config) { c.Router = Router{} },
+			request:  RouteRequest{Model: "claude-sonnet-4"},
+			slot:     "default",
+			resolved: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := simulatorConfig()
+			if tt.config != nil {
+				tt.config(&config)
+			}
+
+			got := simulateRoute(config, tt.request)
+			if got.Slot != tt.slot || got.Provider != tt.provider || got.Model != tt.model || got.Resolved != tt.resolved {
+				t.Errorf("simulateRoute() = slot %q, %q,%q resolved %v; want slot %q, %q,%q resolved %v\nsteps:\n%s",
+					got.Slot, got.Provider, got.Model, got.Resolved,
+					tt.slot, tt.provider, tt.model, tt.resolved,
+					strings.Join(got.Steps, "\n"))
+			}
+			if len(got.Steps) == 0 {
+				t.Errorf("simulateRoute() returned no explanation")
+			}
+		})
+	}
+}
+
+func TestSimulateRouteReportsFallback(t *testing.T) {
+	config := simulatorConfig()
+	config.Fallback = map[string][]string{
+		"default": {"gemini,gemini-2.5-flash", "ollama,qwen2.5-coder:latest"},
+	}
+
+	got := simulateRoute(config, RouteRequest{Model: "claude-sonnet-4"})
+	if got.Slot != "default" || strings.Join(got.Fallback, ";") != "gemini,gemini-2.5-flash;ollama,qwen2.5-coder:latest" {
+		t.Fatalf("simulateRoute() = slot %q fallback %v", got.Slot, got.Fallback)
+	}
+
+	got = simulateRoute(config, RouteRequest{Model: "claude-sonnet-4", Thinking: true})
+	if len(got.Fallback) != 0 {
+		t.Errorf("think route reported fallback %v of another slot", got.Fallback)
+	}
+}
+
+func TestSimulateRouteExplainsSkippedRules(t *testing.T) {
+	config := simulatorConfig()
+	config.CUSTOM_ROUTER_PATH = "~/.claude-code-router/custom-router.js"
+
+	got := simulateRoute(config, RouteRequest{Model: "claude-sonnet-4", Thinking: true})
+
+	want := []string{"custom router", "longContext: skipped", "background: skipped", "webSearch: skipped", "image: skipped", "think: thinking is enabled"}
+	if len(got.Steps) != len(want) {
+		t.Fatalf("got %d steps, want %d:\n%s", len(got.Steps), len(want), strings.Join(got.Steps, "\n"))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(got.Steps[i], prefix) {
+			t.Errorf("step %d = %q, want prefix %q", i, got.Steps[i], prefix)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// routerSlots lists the Router slots modelled as fields that name a
+// "provider,model" pair. Other slots live in Router.Slots.
+var routerSlots = []string{"default", "background", "think", "longContext", "webSearch", "image"}
+
+// RouteResolution describes how a single Router slot resolves against Providers
+type RouteResolution struct {
+	Slot      string `json:"slot"`
+	Reference string `json:"reference"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Resolved  bool   `json:"resolved"`
+	Message   string `json:"message,omitempty"`
+}
+
+// slot returns the reference stored in the named Router slot
+func (r Router) slot(name string) string {
+	switch name {
+	case "default":
+		return r.Default
+	case "background":
+		return r.Background
+	case "think":
+		return r.Think
+	case "longContext":
+		return r.LongContext
+	case "webSearch":
+		return r.WebSearch
+	case "image":
+		return r.Image
+	}
+	return r.Slots[name]
+}
+
+// extraSlotNames lists the slots kept in Slots, sorted by name
+func (r Router) extraSlotNames() []string {
+	names := make([]string, 0, len(r.Slots))
+	for name := range r.Slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// slotNames lists every slot of the router, the modelled ones first
+func (r Router) slotNames() []string {
+	return append(append([]string{}, routerSlots...), r.extraSlotNames()...)
+}
+
+// setSlot stores a reference in the named Router slot
+func (r *Router) setSlot(name, value string) {
+	switch name {
+	case "default":
+		r.Default = value
+	case "background":
+		r.Background = value
+	case "think":
+		r.Think = value
+	case "longContext":
+		r.LongContext = value
+	case "webSearch":
+		r.WebSearch = value
+	case "image":
+		r.Image = value
+	default:
+		// 复制映射，避免修改调用方持有的路由配置
+		slots := make(map[string]string, len(r.Slots)+1)
+		for key, existing := range r.Slots {
+			slots[key] = existing
+		}
+		slots[name] = value
+		r.Slots = slots
+	}
+}
+
+// parseRouteRef splits a "provider,model" reference
+func parseRouteRef(reference string) (string, string, bool) {
+	parts := strings.SplitN(reference, ",", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	provider := strings.TrimSpace(parts[0])
+	model := strings.TrimSpace(parts[1])
+	if provider == "" || model == "" {
+		return "", "", false
+	}
+	return provider, model, true
+}
+
+// resolveRouteRef resolves a single "provider,model" reference against the providers
+func resolveRouteRef(providers []Provider, slot, reference string) RouteResolution {
+	resolution := RouteResolution{Slot: slot, Reference: reference}
+
+	providerName, model, ok := parseRouteRef(reference)
+	if !ok {
+		resolution.Message = fmt.Sprintf("%q is not in \"provider,model\" form", reference)
+		return resolution
+	}
+	resolution.Provider = providerName
+	resolution.Model = model
+
+	var names []string
+	for _, provider := range providers {
+		names = append(names, provider.Name)
+		if provider.Name != providerName {
+			continue
+		}
+
+		for _, candidate := range provider.Models {
+			if candidate == model {
+				resolution.Resolved = true
+				return resolution
+			}
+		}
+
+		resolution.Message = fmt.Sprintf("model %q is not listed by provider %q", model, providerName)
+		if suggestion := closestMatch(model, provider.Models); suggestion != "" {
+			resolution.Message += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		return resolution
+	}
+
+	resolution.Message = fmt.Sprintf("provider %q does not exist", providerName)
+	if suggestion := closestMatch(providerName, names); suggestion != "" {
+		resolution.Message += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+	return resolution
+}
+
+// resolveRoutes resolves every configured Router slot
+func resolveRoutes(config Config) []RouteResolution {
+	resolutions := []RouteResolution{}
+	for _, slot := range config.Router.slotNames() {
+		reference := config.Router.slot(slot)
+		if reference == "" {
+			continue
+		}
+		resolutions = append(resolutions, resolveRouteRef(config.Providers, slot, reference))
+	}
+	return resolutions
+}
+
+// checkRouterReferences reports Router slots that do not resolve to a
+// configured provider and model
+func checkRouterReferences(config Config) []ValidationIssue {
+	var issues []ValidationIssue
+
+	if config.Router.Default == "" && len(config.Providers) > 0 {
+		issues = append(issues, ValidationIssue{
+			Path:     "Router.default",
+			Severity: SeverityWarning,
+			Message:  "no default route is set",
+		})
+	}
+
+	for _, resolution := range resolveRoutes(config) {
+		if resolution.Resolved {
+			continue
+		}
+		issues = append(issues, ValidationIssue{
+			Path:     "Router." + resolution.Slot,
+			Severity: SeverityError,
+			Message:  resolution.Message,
+		})
+	}
+
+	issues = append(issues, checkFallbackChains(config)...)
+
+	return issues
+}
+
+// checkFallbackChains reports fallback entries that do not resolve, chains
+// for slots that are not set and entries that repeat within a chain
+func checkFallbackChains(config Config) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(path, severity, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Path: path, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	slots := make([]string, 0, len(config.Fallback))
+	for slot := range config.Fallback {
+		slots = append(slots, slot)
+	}
+	sort.Strings(slots)
+
+	for _, slot := range slots {
+		chain := config.Fallback[slot]
+		primary := config.Router.slot(slot)
+		if primary == "" && len(chain) > 0 {
+			add("fallback."+slot, SeverityWarning, "slot %q has a fallback chain but no route", slot)
+		}
+
+		seen := map[string]bool{primary: primary != ""}
+		for i, reference := range chain {
+			path := fmt.Sprintf("fallback.%s[%d]", slot, i)
+			resolution := resolveRouteRef(config.Providers, slot, reference)
+			switch {
+			case !resolution.Resolved:
+				add(path, SeverityError, "%s", resolution.Message)
+			case seen[reference]:
+				add(path, SeverityWarning, "%s is already tried earlier for slot %q", reference, slot)
+			}
+			seen[reference] = true
+		}
+	}
+
+	return issues
+}
+
+// closestMatch returns the candidate most similar to value, if any is close
+// enough to be a likely typo
+func closestMatch(value string, candidates []string) string {
+	best := ""
+	bestDistance := 0
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate, value) {
+			return candidate
+		}
+		distance := editDistance(strings.ToLower(value), strings.ToLower(candidate))
+		if best == "" || distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	// 只有差异足够小时才认为是拼写错误
+	limit := len(value) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	if best == "" || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance computes the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// ResolveRoutes resolves every Router slot of config to its provider and model
+func (a *App) ResolveRoutes(config Config) []RouteResolution {
+	return resolveRoutes(config)
+}
+
+// RenameProvider renames a provider and rewrites every Router reference to it
+func (a *App) RenameProvider(config Config, oldName, newName string) (Config, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return config, fmt.Errorf("new provider name is required")
+	}
+	if strings.Contains(newName, ",") {
+		return config, fmt.Errorf("provider name must not contain a comma")
+	}
+
+	index := -1
+	for i, provider := range config.Providers {
+		if provider.Name == newName && newName != oldName {
+			return config, fmt.Errorf("provider %q already exists", newName)
+		}
+		if provider.Name == oldName {
+			index = i
+		}
+	}
+	if index < 0 {
+		return config, fmt.Errorf("provider %q not found", oldName)
+	}
+
+	// 复制切片，避免修改调用方持有的配置
+	providers := make([]Provider, len(config.Providers))
+	copy(providers, config.Providers)
+	providers[index].Name = newName
+	config.Providers = providers
+
+	for _, slot := range config.Router.slotNames() {
+		provider, model, ok := parseRouteRef(config.Router.slot(slot))
+		if ok && provider == oldName {
+			config.Router.setSlot(slot, newName+","+model)
+		}
+	}
+	if len(config.Fallback) > 0 {
+		fallback := make(map[string][]string, len(config.Fallback))
+		for slot, chain := range config.Fallback {
+			renamed := make([]string, len(chain))
+			for i, reference := range chain {
+				renamed[i] = reference
+				if provider, model, ok := parseRouteRef(reference); ok && provider == oldName {
+					renamed[i] = newName + "," + model
+				}
+			}
+			fallback[slot] = renamed
+		}
+		config.Fallback = fallback
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Renamed provider %q to %q", oldName, newName)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultBackupRetention  = 20
+	defaultHistoryRetention = 200
+)
+
+// ManagerSettings holds preferences of the config manager itself. They are
+// kept apart from config.json so CCR never sees them.
+type ManagerSettings struct {
+	// BackupRetention is how many config backups are kept, 0 keeps the default
+	BackupRetention int `json:"backupRetention"`
+	// HistoryRetention is how many config revisions are kept, 0 keeps the default
+	HistoryRetention int `json:"historyRetention"`
+	// ServiceMode is ServiceModeCLI or ServiceModeSupervised, empty for CLI
+	ServiceMode string `json:"serviceMode,omitempty"`
+	// Watchdog restarts CCR when it exits unexpectedly
+	Watchdog bool `json:"watchdog"`
+	// WatchdogMaxCrashes is how many crashes within WatchdogWindowMinutes
+	// make the watchdog give up, 0 keeps the default
+	WatchdogMaxCrashes int `json:"watchdogMaxCrashes"`
+	// WatchdogWindowMinutes is the window crashes are counted in, 0 keeps
+	// the default
+	WatchdogWindowMinutes int `json:"watchdogWindowMinutes"`
+}
+
+// GetSettingsPath returns the path to the config manager settings file
+func (a *App) GetSettingsPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "config-manager.json")
+}
+
+// applySettingsDefaults fills in defaults for unset settings
+func (s ManagerSettings) applySettingsDefaults() ManagerSettings {
+	if s.BackupRetention <= 0 {
+		s.BackupRetention = defaultBackupRetention
+	}
+	if s.HistoryRetention <= 0 {
+		s.HistoryRetention = defaultHistoryRetention
+	}
+	if s.ServiceMode == "" {
+		s.ServiceMode = ServiceModeCLI
+	}
+	if s.WatchdogMaxCrashes <= 0 {
+		s.WatchdogMaxCrashes = defaultWatchdogMaxCrashes
+	}
+	if s.WatchdogWindowMinutes <= 0 {
+		s.WatchdogWindowMinutes = defaultWatchdogWindowMinutes
+	}
+	return s
+}
+
+// GetManagerSettings loads the config manager settings
+func (a *App) GetManagerSettings() (ManagerSettings, error) {
+	var settings ManagerSettings
+
+	settingsPath := a.GetSettingsPath()
+	if settingsPath == "" {
+		return settings.applySettingsDefaults(), fmt.Errorf("could not determine settings path")
+	}
+
+	data, err := os.ReadFile(settingsPath)
+	if os.IsNotExist(err) {
+		return settings.applySettingsDefaults(), nil
+	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to read settings at %s: %v", settingsPath, err)
+		}
+		return settings.applySettingsDefaults(), err
+	}
+
+	if err := json.Unmarshal(data, &settings); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to parse settings at %s: %v", settingsPath, err)
+		}
+		return ManagerSettings{}.applySettingsDefaults(), err
+	}
+
+	return settings.applySettingsDefaults(), nil
+}
+
+// SaveManagerSettings saves the config manager settings
+func (a *App) SaveManagerSettings(settings ManagerSettings) error {
+	settingsPath := a.GetSettingsPath()
+	if settingsPath == "" {
+		return fmt.Errorf("could not determine settings path")
+	}
+
+	if settings.BackupRetention < 0 || settings.HistoryRetention < 0 {
+		return fmt.Errorf("retention counts must not be negative")
+	}
+	if settings.WatchdogMaxCrashes < 0 || settings.WatchdogWindowMinutes < 0 {
+		return fmt.Errorf("watchdog limits must not be negative")
+	}
+	if settings.ServiceMode != "" && settings.ServiceMode != ServiceModeCLI && settings.ServiceMode != ServiceModeSupervised {
+		return fmt.Errorf("unknown service mode %q", settings.ServiceMode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(settingsPath, data, 0644); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write settings to %s: %v", settingsPath, err)
+		}
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Successfully saved settings to %s", settingsPath)
+	}
+
+	return nil
+}
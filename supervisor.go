@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// How StartService, StopService and RestartService control CCR
+const (
+	// ServiceModeCLI runs `ccr start`, `ccr stop` and `ccr restart`
+	ServiceModeCLI = "cli"
+	// ServiceModeSupervised runs the CCR server as a child of the manager
+	ServiceModeSupervised = "supervised"
+)
+
+// Lifecycle states of the supervised CCR process
+const (
+	SupervisorStopped  = "stopped"
+	SupervisorStarting = "starting"
+	SupervisorRunning  = "running"
+	SupervisorStopping = "stopping"
+	SupervisorCrashed  = "crashed"
+)
+
+const (
+	// EventServiceState is emitted with a SupervisorStatus whenever the
+	// supervised process changes state
+	EventServiceState = "service:state"
+	// EventServiceOutput is emitted with each OutputLine the process writes
+	EventServiceOutput = "service:output"
+
+	// outputBufferSize is how many output lines are kept
+	outputBufferSize = 1000
+	// stopGracePeriod is how long CCR may take to exit before it is killed
+	stopGracePeriod = 5 * time.Second
+	// readyPollInterval is how often the port is checked while starting
+	readyPollInterval = 250 * time.Millisecond
+	// outputDrainTimeout is how long output is still read after the process
+	// exited, children may keep the pipes open
+	outputDrainTimeout = 2 * time.Second
+)
+
+// SupervisorStatus describes the supervised CCR process
+type SupervisorStatus struct {
+	Mode      string   `json:"mode"`
+	State     string   `json:"state"`
+	PID       int      `json:"pid,omitempty"`
+	Command   []string `json:"command,omitempty"`
+	StartedAt string   `json:"startedAt,omitempty"`
+	ExitedAt  string   `json:"exitedAt,omitempty"`
+	ExitCode  int      `json:"exitCode"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// OutputLine is a line the supervised process wrote to stdout or stderr
+type OutputLine struct {
+	Time   string `json:"time"`
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+// supervisor owns the CCR child process in ServiceModeSupervised
+type supervisor struct {
+	mu        sync.Mutex
+	state     string
+	cmd       *exec.Cmd
+	command   []string
+	startedAt time.Time
+	exitedAt  time.Time
+	exitCode  int
+	err       string
+	// done is closed when the current process has exited
+	done chan struct{}
+
+	// output is a ring buffer of the last outputBufferSize lines
+	output     []OutputLine
+	outputNext int
+	// outputCount counts every line appended, runOutput is the count when
+	// the current process started
+	outputCount int
+	runOutput   int
+}
+
+// statusLocked snapshots the supervisor. The caller must hold mu.
+func (s *supervisor) statusLocked() SupervisorStatus {
+	status := SupervisorStatus{
+		State:    s.state,
+		Command:  s.command,
+		ExitCode: s.exitCode,
+		Error:    s.err,
+	}
+	if status.State == "" {
+		status.State = SupervisorStopped
+	}
+	if s.cmd != nil && s.cmd.Process != nil {
+		status.PID = s.cmd.Process.Pid
+	}
+	if !s.startedAt.IsZero() {
+		status.StartedAt = s.startedAt.Format(time.RFC3339)
+	}
+	if !s.exitedAt.IsZero() {
+		status.ExitedAt = s.exitedAt.Format(time.RFC3339)
+	}
+	return status
+}
+
+// alive reports whether a process is starting, running or stopping. The
+// caller must hold mu.
+func (s *supervisor) alive() bool {
+	return s.state == SupervisorStarting || s.state == SupervisorRunning || s.state == SupervisorStopping
+}
+
+// appendOutput stores a line in the ring buffer. The caller must hold mu.
+func (s *supervisor) appendOutput(line OutputLine) {
+	s.outputCount++
+	if len(s.output) < outputBufferSize {
+		s.output = append(s.output, line)
+		return
+	}
+	s.output[s.outputNext] = line
+	s.outputNext = (s.outputNext + 1) % outputBufferSize
+}
+
+// outputLocked returns the buffered lines, oldest first. The caller must
+// hold mu.
+func (s *supervisor) outputLocked() []OutputLine {
+	lines := make([]OutputLine, 0, len(s.output))
+	lines = append(lines, s.output[s.outputNext:]...)
+	return append(lines, s.output[:s.outputNext]...)
+}
+
+// lastRunOutput returns up to n of the last lines written by the current
+// process. The caller must hold mu.
+func (s *supervisor) lastRunOutput(n int) []string {
+	lines := s.outputLocked()
+	if written := s.outputCount - s.runOutput; written < n {
+		n = written
+	}
+	if n > len(lines) {
+		n = len(lines)
+	}
+	text := make([]string, 0, n)
+	for _, line := range lines[len(lines)-n:] {
+		text = append(text, line.Text)
+	}
+	return text
+}
+
+// serviceMode returns the configured service mode
+func (a *App) serviceMode() string {
+	settings, _ := a.GetManagerSettings()
+	return settings.ServiceMode
+}
+
+// emitServiceState publishes the supervisor state to the frontend. The
+// caller must hold supervisor.mu.
+func (a *App) emitServiceState() {
+	status := a.supervisor.statusLocked()
+	status.Mode = ServiceModeSupervised
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, EventServiceState, status)
+	}
+}
+
+// supervisedCommand returns the command running the CCR server in the
+// foreground: node with CCR's cli.js when both can be found, else `ccr start`
+func (a *App) supervisedCommand(config Config) ([]string, error) {
+	ccrPath, err := a.findCCRPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find CCR path: %v", err)
+	}
+
+	cliPath := filepath.Join(filepath.Dir(ccrPath), "node_modules", "@musistudio", "claude-code-router", "dist", "cli.js")
+	if node := findNode(config); node != "" {
+		if _, err := os.Stat(cliPath); err == nil {
+			return []string{node, cliPath, "start"}, nil
+		}
+	}
+	return []string{ccrPath, "start"}, nil
+}
+
+// captureOutput copies the lines of r into the output buffer and emits them
+func (a *App) captureOutput(stream string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := OutputLine{
+			Time:   time.Now().Format(time.RFC3339),
+			Stream: stream,
+			Text:   redactSecrets(scanner.Text()),
+		}
+		a.supervisor.mu.Lock()
+		a.supervisor.appendOutput(line)
+		a.supervisor.mu.Unlock()
+		if a.ctx != nil {
+			wailsruntime.EventsEmit(a.ctx, EventServiceOutput, line)
+		}
+	}
+}
+
+// startSupervised spawns the CCR server and returns once it is started. It
+// becomes running when its port accepts connections.
+func (a *App) startSupervised() error {
+	config, _, err := a.readConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+	command, err := a.supervisedCommand(config)
+	if err != nil {
+		return err
+	}
+
+	// 查询端口和准备配置可能耗时较久，不持有 supervisor.mu，以免阻塞状态查询和输出采集
+	if a.supervisorActive() {
+		return fmt.Errorf("CCR is already running under the manager")
+	}
+	if pid, running, _ := a.findProcessByPort(config.PORT); running {
+		return fmt.Errorf("port %d is already in use by PID %d", config.PORT, pid)
+	}
+
+	// 临时写入解析了密钥引用的配置，服务监听端口后恢复
+	restore, err := a.materializeConfig()
+	if err != nil {
+		return fmt.Errorf("failed to prepare config for CCR: %v", err)
+	}
+
+	a.supervisor.mu.Lock()
+	defer a.supervisor.mu.Unlock()
+
+	// 等待期间可能已由其他调用启动
+	if a.supervisor.alive() {
+		restore()
+		return fmt.Errorf("CCR is already %s", a.supervisor.state)
+	}
+
+	// 输出经由管道复制，进程退出后最多再等待 outputDrainTimeout，
+	// 避免仍持有管道的子进程使 Wait 永远阻塞
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = "."
+	cmd.SysProcAttr = supervisedSysProcAttr()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	cmd.WaitDelay = outputDrainTimeout
+	if err := cmd.Start(); err != nil {
+		restore()
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to start CCR: %v", err)
+		}
+		return fmt.Errorf("failed to start CCR: %v", err)
+	}
+
+	done := make(chan struct{})
+	a.supervisor.cmd = cmd
+	a.supervisor.command = command
+	a.supervisor.state = SupervisorStarting
+	a.supervisor.startedAt = time.Now()
+	a.supervisor.exitedAt = time.Time{}
+	a.supervisor.exitCode = 0
+	a.supervisor.err = ""
+	a.supervisor.done = done
+	a.supervisor.runOutput = a.supervisor.outputCount
+	a.emitServiceState()
+
+	if a.logger != nil {
+		a.logger.Printf("Started CCR with PID %d: %v", cmd.Process.Pid, command)
+	}
+
+	var pipes sync.WaitGroup
+	pipes.Add(2)
+	go func() { defer pipes.Done(); a.captureOutput("stdout", stdoutReader) }()
+	go func() { defer pipes.Done(); a.captureOutput("stderr", stderrReader) }()
+
+	go restore()
+	go a.waitUntilReady(cmd, done, net.JoinHostPort(probeHost(config), strconv.Itoa(config.PORT)))
+	go func() {
+		err := cmd.Wait()
+		if errors.Is(err, exec.ErrWaitDelay) {
+			// 进程已退出，只是仍有子进程持有输出管道
+			err = nil
+		}
+		// 结束仍在运行的子进程，避免其继续占用端口
+		killOrphans(cmd.Process)
+		// 关闭写端后采集协程读完剩余输出即结束，崩溃记录包含完整输出
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		pipes.Wait()
+		a.handleExit(cmd, err, done)
+	}()
+
+	return nil
+}
+
+// waitUntilReady marks the process running once address accepts connections
+func (a *App) waitUntilReady(cmd *exec.Cmd, done chan struct{}, address string) {
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		conn, err := net.DialTimeout("tcp", address, readyPollInterval)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+
+		a.supervisor.mu.Lock()
+		if a.supervisor.cmd == cmd && a.supervisor.state == SupervisorStarting {
+			a.supervisor.state = SupervisorRunning
+			a.emitServiceState()
+			if a.logger != nil {
+				a.logger.Printf("CCR is listening on %s", address)
+			}
+		}
+		a.supervisor.mu.Unlock()
+		return
+	}
+}
+
+// handleExit records how the process ended. Exits not requested by
+// stopSupervised count as crashes.
+func (a *App) handleExit(cmd *exec.Cmd, err error, done chan struct{}) {
+	a.supervisor.mu.Lock()
+	defer a.supervisor.mu.Unlock()
+	defer close(done)
+
+	if a.supervisor.cmd != cmd {
+		return
+	}
+	a.supervisor.exitedAt = time.Now()
+	a.supervisor.exitCode = cmd.ProcessState.ExitCode()
+	if a.supervisor.state == SupervisorStopping {
+		a.supervisor.state = SupervisorStopped
+	} else {
+		a.supervisor.state = SupervisorCrashed
+		if err != nil {
+			a.supervisor.err = err.Error()
+		} else {
+			a.supervisor.err = "CCR exited unexpectedly"
+		}
+	}
+	a.emitServiceState()
+
+	if a.logger != nil {
+		a.logger.Printf("CCR (PID %d) exited with code %d, state %s", cmd.Process.Pid, a.supervisor.exitCode, a.supervisor.state)
+	}
+
+	if a.supervisor.state == SupervisorCrashed {
+		crash := CrashRecord{
+			Mode:     ServiceModeSupervised,
+			PID:      cmd.Process.Pid,
+			ExitCode: a.supervisor.exitCode,
+			Reason:   a.supervisor.err,
+			Output:   a.supervisor.lastRunOutput(crashOutputLines),
+		}
+		// 看门狗会读取配置并可能重新启动，不能持有 supervisor.mu
+		go a.reportCrash(crash)
+	}
+}
+
+// stopSupervised asks the process to exit and kills it after
+// stopGracePeriod. It returns once the process has exited.
+func (a *App) stopSupervised() error {
+	a.supervisor.mu.Lock()
+	if !a.supervisor.alive() {
+		a.supervisor.mu.Unlock()
+		return nil
+	}
+	cmd, done := a.supervisor.cmd, a.supervisor.done
+	a.supervisor.state = SupervisorStopping
+	a.emitServiceState()
+	a.supervisor.mu.Unlock()
+
+	if a.logger != nil {
+		a.logger.Printf("Stopping CCR (PID %d)", cmd.Process.Pid)
+	}
+
+	if err := interruptProcess(cmd.Process); err != nil {
+		killProcessTree(cmd.Process)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopGracePeriod):
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("WARNING: CCR did not exit within %v, killing it", stopGracePeriod)
+	}
+	if err := killProcessTree(cmd.Process); err != nil {
+		return fmt.Errorf("failed to kill CCR: %v", err)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopGracePeriod):
+		return fmt.Errorf("CCR (PID %d) did not exit after being killed", cmd.Process.Pid)
+	}
+}
+
+// supervisorActive reports whether the supervisor has a live process
+func (a *App) supervisorActive() bool {
+	a.supervisor.mu.Lock()
+	defer a.supervisor.mu.Unlock()
+	return a.supervisor.alive()
+}
+
+// GetSupervisorStatus reports the mode and the state of the supervised CCR
+// process
+func (a *App) GetSupervisorStatus() SupervisorStatus {
+	a.supervisor.mu.Lock()
+	status := a.supervisor.statusLocked()
+	a.supervisor.mu.Unlock()
+
+	status.Mode = a.serviceMode()
+	return status
+}
+
+// GetServiceOutput returns the buffered output of the supervised process,
+// oldest first
+func (a *App) GetServiceOutput() []OutputLine {
+	a.supervisor.mu.Lock()
+	defer a.supervisor.mu.Unlock()
+	return a.supervisor.outputLocked()
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// supervisedSysProcAttr starts the supervised process in its own process
+// group, so the node process behind the ccr shim is stopped with it
+func supervisedSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptProcess asks the process group of p to exit gracefully
+func interruptProcess(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGINT)
+}
+
+// killProcessTree kills the process group of p
+func killProcessTree(p *os.Process) error {
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// killOrphans kills what is left of the process group of p after p exited
+func killOrphans(p *os.Process) {
+	killProcessTree(p)
+}
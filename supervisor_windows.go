@@ -0,0 +1,37 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// supervisedSysProcAttr hides the console window of the supervised process
+func supervisedSysProcAttr() *syscall.SysProcAttr {
+	return getSysProcAttr()
+}
+
+// interruptProcess stops a process. Windows has no signal a console-less
+// child can handle, so the process tree is killed.
+func interruptProcess(p *os.Process) error {
+	return killProcessTree(p)
+}
+
+// killProcessTree kills p and its children, such as node started by
+// ccr.cmd
+func killProcessTree(p *os.Process) error {
+	cmd := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid))
+	cmd.SysProcAttr = getSysProcAttr()
+	if err := cmd.Run(); err != nil {
+		// taskkill 失败时至少结束直接子进程
+		return p.Kill()
+	}
+	return nil
+}
+
+// killOrphans does nothing on Windows: once p exited its PID may be reused,
+// so the tree can no longer be found safely
+func killOrphans(p *os.Process) {}
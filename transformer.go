@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// TransformerInfo describes a transformer that can be used by providers
+type TransformerInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Options     []string `json:"options,omitempty"`
+	Builtin     bool     `json:"builtin"`
+}
+
+// builtinTransformers lists the transformers CCR ships
+var builtinTransformers = []TransformerInfo{
+	{Name: "anthropic", Description: "Pass requests through in Anthropic format"},
+	{Name: "deepseek", Description: "Adapt requests and responses for the DeepSeek API"},
+	{Name: "gemini", Description: "Adapt requests and responses for the Gemini API"},
+	{Name: "vertex-gemini", Description: "Gemini models on Vertex AI"},
+	{Name: "vertex-claude", Description: "Claude models on Vertex AI"},
+	{Name: "openrouter", Description: "Adapt requests and responses for OpenRouter", Options: []string{"provider"}},
+	{Name: "openai", Description: "Plain OpenAI chat completions"},
+	{Name: "groq", Description: "Adapt requests and responses for Groq"},
+	{Name: "cerebras", Description: "Adapt requests and responses for Cerebras"},
+	{Name: "vercel", Description: "Adapt requests and responses for the Vercel AI gateway"},
+	{Name: "tooluse", Description: "Encourage tool use through tool_choice"},
+	{Name: "maxtoken", Description: "Set a specific max_tokens value", Options: []string{"max_tokens"}},
+	{Name: "reasoning", Description: "Map reasoning content to thinking blocks"},
+	{Name: "sampling", Description: "Forward sampling parameters such as temperature and top_p"},
+	{Name: "enhancetool", Description: "Tolerate malformed tool call arguments"},
+	{Name: "cleancache", Description: "Remove cache_control fields from requests"},
+	{Name: "customparams", Description: "Add custom request parameters"},
+	{Name: "streamoptions", Description: "Request usage in streamed responses"},
+}
+
+// TransformerRef is one entry of a transformer use list: a transformer name,
+// optionally with options. In config.json it is written as "name" or
+// ["name", {options}].
+type TransformerRef struct {
+	Name    string                 `json:"name"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// ModelTransformer overrides the transformers used for a single model
+type ModelTransformer struct {
+	Model string           `json:"model"`
+	Use   []TransformerRef `json:"use"`
+
+	fields objectFields
+}
+
+// ProviderTransformer is the transformer configuration of a provider: a use
+// list for every model plus per-model overrides, kept in file order
+type ProviderTransformer struct {
+	Use    []TransformerRef   `json:"use"`
+	Models []ModelTransformer `json:"models"`
+}
+
+// UnmarshalJSON accepts "name", ["name", {options}] and {"name", "options"}
+func (t *TransformerRef) UnmarshalJSON(data []byte) error {
+	*t = TransformerRef{}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		t.Name = name
+		return nil
+	}
+
+	var tuple []json.RawMessage
+	if err := json.Unmarshal(data, &tuple); err == nil {
+		if len(tuple) == 0 || len(tuple) > 2 {
+			return fmt.Errorf("transformer entry must be a name or [name, options]")
+		}
+		if err := json.Unmarshal(tuple[0], &t.Name); err != nil {
+			return fmt.Errorf("transformer name must be a string")
+		}
+		if len(tuple) == 2 {
+			return t.decodeOptions(tuple[1])
+		}
+		return nil
+	}
+
+	// 前端绑定调用使用的对象形式
+	var object struct {
+		Name    string          `json:"name"`
+		Options json.RawMessage `json:"options"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil || object.Name == "" {
+		return fmt.Errorf("transformer entry must be a name or [name, options]")
+	}
+	t.Name = object.Name
+	if len(object.Options) > 0 {
+		return t.decodeOptions(object.Options)
+	}
+	return nil
+}
+
+// decodeOptions decodes the options object of a transformer entry
+func (t *TransformerRef) decodeOptions(raw json.RawMessage) error {
+	options, err := decodeValue(raw)
+	if err != nil {
+		return err
+	}
+	if options == nil {
+		return nil
+	}
+	optionsMap, ok := options.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("options of transformer %q must be an object", t.Name)
+	}
+	t.Options = optionsMap
+	return nil
+}
+
+// MarshalJSON writes the entry in the form CCR reads
+func (t TransformerRef) MarshalJSON() ([]byte, error) {
+	if t.Options == nil {
+		return json.Marshal(t.Name)
+	}
+	return json.Marshal([]interface{}{t.Name, t.Options})
+}
+
+// UnmarshalJSON decodes {"use": [...], "<model>": {"use": [...]}}. Keys of a
+// model override other than use are kept as they are.
+func (t *ProviderTransformer) UnmarshalJSON(data []byte) error {
+	*t = ProviderTransformer{}
+
+	fields, err := decodeObject(data)
+	if err != nil {
+		return fmt.Errorf("transformer must be an object: %v", err)
+	}
+
+	for _, field := range fields {
+		if field.Key == "use" {
+			if err := json.Unmarshal(field.Value, &t.Use); err != nil {
+				return fmt.Errorf("use: %v", err)
+			}
+			continue
+		}
+
+		// 其余键均为模型名，值为该模型的转换器配置
+		overrideFields, err := decodeObject(field.Value)
+		if err != nil {
+			return fmt.Errorf("%s: expected an object with a use list", field.Key)
+		}
+		override := ModelTransformer{Model: field.Key, Use: []TransformerRef{}}
+		for _, overrideField := range overrideFields {
+			if overrideField.Key != "use" {
+				continue
+			}
+			if err := json.Unmarshal(overrideField.Value, &override.Use); err != nil {
+				return fmt.Errorf("%s.use: %v", field.Key, err)
+			}
+		}
+		// 除 use 以外的键原样保留，写回时不丢失
+		override.fields.capture(overrideFields, func(key string) bool { return key == "use" })
+		t.Models = append(t.Models, override)
+	}
+
+	return nil
+}
+
+// MarshalJSON writes the global use list first, then the model overrides
+func (t ProviderTransformer) MarshalJSON() ([]byte, error) {
+	var fields []rawField
+	if t.Use != nil {
+		use, err := json.Marshal(t.Use)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, rawField{Key: "use", Value: use})
+	}
+	for _, override := range t.Models {
+		use := override.Use
+		if use == nil {
+			use = []TransformerRef{}
+		}
+		value, err := json.Marshal(use)
+		if err != nil {
+			return nil, err
+		}
+		// 只有其他设置的覆盖项不补写空的 use
+		var known []rawField
+		if len(use) > 0 || override.fields.hasKey("use") || len(override.fields.extra) == 0 {
+			known = append(known, rawField{Key: "use", Value: value})
+		}
+		fields = append(fields, rawField{Key: override.Model, Value: encodeObject(override.fields.arrange(known))})
+	}
+	return encodeObject(fields), nil
+}
+
+// clone copies the override list so edits do not alias the caller's slices
+func (t ProviderTransformer) clone() ProviderTransformer {
+	models := make([]ModelTransformer, len(t.Models))
+	copy(models, t.Models)
+	t.Models = models
+	return t
+}
+
+// useList returns the use list for model, "" meaning the provider-wide list
+func (t *ProviderTransformer) useList(model string) *[]TransformerRef {
+	if model == "" {
+		return &t.Use
+	}
+	for i := range t.Models {
+		if t.Models[i].Model == model {
+			return &t.Models[i].Use
+		}
+	}
+	return nil
+}
+
+// dropEmptyOverrides removes model overrides without transformers or other
+// settings
+func (t *ProviderTransformer) dropEmptyOverrides() {
+	models := t.Models[:0]
+	for _, override := range t.Models {
+		if len(override.Use) > 0 || len(override.fields.extra) > 0 {
+			models = append(models, override)
+		}
+	}
+	t.Models = models
+}
+
+// knownTransformerNames returns the names of the transformers available to
+// config: the built-in ones and those of registered plugins
+func knownTransformerNames(config Config) map[string]bool {
+	names := make(map[string]bool)
+	for _, info := range builtinTransformers {
+		names[info.Name] = true
+	}
+	for _, plugin := range config.Transformers {
+		names[inspectPlugin(plugin).Name] = true
+	}
+	return names
+}
+
+// validateTransformerRefs checks one use list
+func validateTransformerRefs(path string, refs []TransformerRef, known map[string]bool, add func(path, severity, format string, args ...interface{})) {
+	seen := make(map[string]bool)
+	for i, ref := range refs {
+		refPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case ref.Name == "":
+			add(refPath, SeverityError, "transformer name is required")
+			continue
+		case !known[ref.Name]:
+			add(refPath, SeverityWarning, "unknown transformer %q", ref.Name)
+		case seen[ref.Name]:
+			add(refPath, SeverityWarning, "transformer %q is used more than once", ref.Name)
+		}
+		seen[ref.Name] = true
+
+		if ref.Name == "maxtoken" {
+			maxTokens, ok := ref.Options["max_tokens"].(json.Number)
+			if value, err := maxTokens.Int64(); !ok || err != nil || value <= 0 {
+				add(refPath, SeverityError, "maxtoken requires a positive integer max_tokens option")
+			}
+		}
+	}
+}
+
+// validateTransformer checks the transformer configuration of a provider
+func validateTransformer(path string, provider Provider, known map[string]bool, add func(path, severity, format string, args ...interface{})) {
+	if provider.Transformer == nil {
+		return
+	}
+	t := provider.Transformer
+
+	validateTransformerRefs(path+".use", t.Use, known, add)
+
+	models := make(map[string]bool)
+	for _, model := range provider.Models {
+		models[model] = true
+	}
+	overrides := make(map[string]bool)
+	for _, override := range t.Models {
+		overridePath := joinPath(path, override.Model)
+		if overrides[override.Model] {
+			add(overridePath, SeverityError, "duplicate transformer override for model %q", override.Model)
+		}
+		overrides[override.Model] = true
+		if !models[override.Model] {
+			add(overridePath, SeverityWarning, "model %q is not in the provider's model list", override.Model)
+		}
+		validateTransformerRefs(overridePath+".use", override.Use, known, add)
+	}
+}
+
+// normalizeTransformerRef re-encodes options so they compare and marshal like
+// values read from config.json
+func normalizeTransformerRef(ref TransformerRef) (TransformerRef, error) {
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return ref, err
+	}
+	var normalized TransformerRef
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// ListTransformers lists the transformers that can be added to a provider of
+// config, including those of registered plugins
+func (a *App) ListTransformers(config Config) []TransformerInfo {
+	infos := make([]TransformerInfo, 0, len(builtinTransformers)+len(config.Transformers))
+	for _, info := range builtinTransformers {
+		info.Builtin = true
+		infos = append(infos, info)
+	}
+	for _, plugin := range config.Transformers {
+		status := inspectPlugin(plugin)
+		if knownBuiltinTransformer(status.Name) {
+			continue
+		}
+		infos = append(infos, TransformerInfo{Name: status.Name, Description: "Plugin " + plugin.Path})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// AddTransformer appends a transformer to the use list of model, or to the
+// provider-wide list when model is empty, and returns the updated transformer
+func (a *App) AddTransformer(transformer ProviderTransformer, model string, ref TransformerRef) (ProviderTransformer, error) {
+	if ref.Name == "" {
+		return transformer, fmt.Errorf("transformer name is required")
+	}
+	ref, err := normalizeTransformerRef(ref)
+	if err != nil {
+		return transformer, err
+	}
+	transformer = transformer.clone()
+
+	list := transformer.useList(model)
+	if list == nil {
+		transformer.Models = append(transformer.Models, ModelTransformer{Model: model})
+		list = &transformer.Models[len(transformer.Models)-1].Use
+	}
+	for _, existing := range *list {
+		if existing.Name == ref.Name {
+			return transformer, fmt.Errorf("transformer %q is already used", ref.Name)
+		}
+	}
+	*list = append(append([]TransformerRef{}, *list...), ref)
+
+	return transformer, nil
+}
+
+// RemoveTransformer removes the transformer at index from the use list of
+// model, or from the provider-wide list when model is empty
+func (a *App) RemoveTransformer(transformer ProviderTransformer, model string, index int) (ProviderTransformer, error) {
+	transformer = transformer.clone()
+	list := transformer.useList(model)
+	if list == nil || index < 0 || index >= len(*list) {
+		return transformer, fmt.Errorf("no transformer at position %d", index)
+	}
+
+	refs := append([]TransformerRef{}, (*list)[:index]...)
+	*list = append(refs, (*list)[index+1:]...)
+	transformer.dropEmptyOverrides()
+
+	return transformer, nil
+}
+
+// MoveTransformer moves a transformer within the use list of model, or of
+// the provider-wide list when model is empty. Transformers run in list order.
+func (a *App) MoveTransformer(transformer ProviderTransformer, model string, from, to int) (ProviderTransformer, error) {
+	transformer = transformer.clone()
+	list := transformer.useList(model)
+	if list == nil || from < 0 || from >= len(*list) || to < 0 || to >= len(*list) {
+		return transformer, fmt.Errorf("invalid transformer position")
+	}
+
+	refs := append([]TransformerRef{}, *list...)
+	ref := refs[from]
+	refs = append(refs[:from], refs[from+1:]...)
+	refs = append(refs[:to], append([]TransformerRef{ref}, refs[to:]...)...)
+	*list = refs
+
+	return transformer, nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pluginNamePattern finds the name a transformer plugin registers under, such
+// as `name = "gemini-cli"` in a class or `name: "gemini-cli"` in an object
+var pluginNamePattern = regexp.MustCompile("\\bname\\s*[=:]\\s*[\"'`]([A-Za-z0-9_.-]+)[\"'`]")
+
+// maxPluginScanSize bounds how much of a plugin file is scanned for its name
+const maxPluginScanSize = 256 * 1024
+
+// TransformerPlugin is an entry of the top-level transformers list, which
+// loads custom transformers from JS files
+type TransformerPlugin struct {
+	Path    string                 `json:"path"`
+	Options map[string]interface{} `json:"options,omitempty"`
+
+	fields objectFields
+}
+
+// TransformerPluginStatus reports whether a registered plugin can be loaded
+type TransformerPluginStatus struct {
+	Path         string                 `json:"path"`
+	ResolvedPath string                 `json:"resolvedPath"`
+	Options      map[string]interface{} `json:"options,omitempty"`
+	Name         string                 `json:"name"`
+	Exists       bool                   `json:"exists"`
+	Readable     bool                   `json:"readable"`
+	ModTime      string                 `json:"modTime,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// pluginKeys lists the plugin keys modelled by TransformerPlugin
+var pluginKeys = map[string]bool{
+	"path":    true,
+	"options": true,
+}
+
+func (d *schemaDecoder) decodePlugin(path string, raw json.RawMessage) TransformerPlugin {
+	var plugin TransformerPlugin
+	fields, ok := d.decodeObjectAt(path, raw)
+	if !ok {
+		return plugin
+	}
+
+	for _, field := range fields {
+		fieldPath := joinPath(path, field.Key)
+		switch field.Key {
+		case "path":
+			plugin.Path = d.decodeString(fieldPath, field.Value)
+		case "options":
+			if bytes.Equal(bytes.TrimSpace(field.Value), []byte("null")) {
+				continue
+			}
+			value, err := decodeValue(field.Value)
+			options, ok := value.(map[string]interface{})
+			if err != nil || !ok {
+				d.fail(fieldPath, "expected an object")
+				continue
+			}
+			plugin.Options = options
+		}
+	}
+
+	plugin.fields.capture(fields, func(key string) bool { return pluginKeys[key] })
+	return plugin
+}
+
+func (d *schemaDecoder) decodePlugins(path string, raw json.RawMessage) []TransformerPlugin {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		d.fail(path, "expected a list of transformer plugins")
+		return nil
+	}
+
+	plugins := make([]TransformerPlugin, 0, len(items))
+	for i, item := range items {
+		plugins = append(plugins, d.decodePlugin(fmt.Sprintf("%s[%d]", path, i), item))
+	}
+	return plugins
+}
+
+// UnmarshalJSON decodes a single plugin, rejecting values that do not fit the schema
+func (p *TransformerPlugin) UnmarshalJSON(data []byte) error {
+	d := &schemaDecoder{}
+	*p = d.decodePlugin("", data)
+	return d.err()
+}
+
+// pluginAlias has the same fields as TransformerPlugin without its JSON methods
+type pluginAlias TransformerPlugin
+
+// MarshalJSON encodes the plugin together with its preserved keys
+func (p TransformerPlugin) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(pluginAlias(p))
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := decodeObject(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeObject(p.fields.arrange(known)), nil
+}
+
+// resolveScriptPath expands environment references and a leading ~ in the
+// path of a JS file CCR loads, such as a plugin or custom router
+func resolveScriptPath(path string) string {
+	resolved, _ := expandEnvRefs(path)
+	if resolved == "~" || strings.HasPrefix(resolved, "~/") || strings.HasPrefix(resolved, `~\`) {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			resolved = filepath.Join(homeDir, resolved[1:])
+		}
+	}
+	return resolved
+}
+
+// inspectPlugin checks that a plugin file exists and is readable and finds
+// the name it registers under, falling back to the file name
+func inspectPlugin(plugin TransformerPlugin) TransformerPluginStatus {
+	status := TransformerPluginStatus{
+		Path:         plugin.Path,
+		ResolvedPath: resolveScriptPath(plugin.Path),
+		Options:      plugin.Options,
+	}
+	base := filepath.Base(status.ResolvedPath)
+	status.Name = strings.TrimSuffix(base, filepath.Ext(base))
+
+	if plugin.Path == "" {
+		status.Error = "plugin path is empty"
+		return status
+	}
+
+	info, err := os.Stat(status.ResolvedPath)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Exists = true
+	status.ModTime = info.ModTime().Format(time.RFC3339)
+	if info.IsDir() {
+		status.Error = "plugin path is a directory"
+		return status
+	}
+
+	file, err := os.Open(status.ResolvedPath)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer file.Close()
+	status.Readable = true
+
+	head := make([]byte, maxPluginScanSize)
+	n, _ := file.Read(head)
+	if match := pluginNamePattern.FindSubmatch(head[:n]); match != nil {
+		status.Name = string(match[1])
+	}
+
+	return status
+}
+
+// validatePlugins checks the transformers list
+func validatePlugins(config Config, add func(path, severity, format string, args ...interface{})) {
+	seen := make(map[string]bool)
+	for i, plugin := range config.Transformers {
+		path := fmt.Sprintf("transformers[%d].path", i)
+		status := inspectPlugin(plugin)
+		switch {
+		case plugin.Path == "":
+			add(path, SeverityError, "plugin path is required")
+		case seen[status.ResolvedPath]:
+			add(path, SeverityWarning, "plugin %s is registered more than once", plugin.Path)
+		case !status.Readable:
+			add(path, SeverityWarning, "plugin file cannot be read: %s", status.Error)
+		}
+		seen[status.ResolvedPath] = true
+	}
+}
+
+// ListTransformerPlugins lists the plugins registered in config and whether
+// their files can be loaded
+func (a *App) ListTransformerPlugins(config Config) []TransformerPluginStatus {
+	statuses := make([]TransformerPluginStatus, 0, len(config.Transformers))
+	for _, plugin := range config.Transformers {
+		statuses = append(statuses, inspectPlugin(plugin))
+	}
+	return statuses
+}
+
+// RegisterTransformerPlugin adds a plugin file to the transformers list and
+// returns the updated config. The file must exist and be readable.
+func (a *App) RegisterTransformerPlugin(config Config, path string, options map[string]interface{}) (Config, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return config, fmt.Errorf("plugin path is required")
+	}
+
+	plugin := TransformerPlugin{Path: path, Options: options}
+	status := inspectPlugin(plugin)
+	if !status.Readable {
+		return config, fmt.Errorf("plugin file cannot be read: %s", status.Error)
+	}
+	for _, existing := range config.Transformers {
+		if resolveScriptPath(existing.Path) == status.ResolvedPath {
+			return config, fmt.Errorf("plugin %s is already registered", path)
+		}
+	}
+	// 选项需与从文件读取的值一致（数字为 json.Number）
+	if options != nil {
+		data, err := json.Marshal(options)
+		if err != nil {
+			return config, err
+		}
+		value, err := decodeValue(data)
+		if err != nil {
+			return config, err
+		}
+		plugin.Options, _ = value.(map[string]interface{})
+	}
+
+	config.Transformers = append(append([]TransformerPlugin{}, config.Transformers...), plugin)
+
+	if a.logger != nil {
+		a.logger.Printf("Registered transformer plugin %s as %s", path, status.Name)
+	}
+	return config, nil
+}
+
+// UnregisterTransformerPlugin removes a plugin from the transformers list and
+// returns the updated config. Plugins whose transformer is still used by a
+// provider are not removed.
+func (a *App) UnregisterTransformerPlugin(config Config, path string) (Config, error) {
+	index := -1
+	for i, plugin := range config.Transformers {
+		if plugin.Path == path {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return config, fmt.Errorf("plugin %s is not registered", path)
+	}
+
+	name := inspectPlugin(config.Transformers[index]).Name
+	if !knownBuiltinTransformer(name) {
+		if users := transformerUsers(config, name); len(users) > 0 {
+			return config, fmt.Errorf("transformer %q is still used by %s", name, strings.Join(users, ", "))
+		}
+	}
+
+	plugins := append([]TransformerPlugin{}, config.Transformers[:index]...)
+	config.Transformers = append(plugins, config.Transformers[index+1:]...)
+
+	if a.logger != nil {
+		a.logger.Printf("Unregistered transformer plugin %s", path)
+	}
+	return config, nil
+}
+
+// knownBuiltinTransformer reports whether name is a transformer CCR ships
+func knownBuiltinTransformer(name string) bool {
+	for _, info := range builtinTransformers {
+		if info.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// transformerUsers lists the providers whose transformer configuration uses
+// the named transformer
+func transformerUsers(config Config, name string) []string {
+	var users []string
+	for _, provider := range config.Providers {
+		if provider.Transformer == nil {
+			continue
+		}
+		lists := [][]TransformerRef{provider.Transformer.Use}
+		for _, override := range provider.Transformer.Models {
+			lists = append(lists, override.Use)
+		}
+	search:
+		for _, list := range lists {
+			for _, ref := range list {
+				if ref.Name == name {
+					users = append(users, provider.Name)
+					break search
+				}
+			}
+		}
+	}
+	return users
+}
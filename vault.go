@@ -0,0 +1,699 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// vaultRefPrefix marks a config value that refers to a vault secret
+	vaultRefPrefix = "vault:"
+
+	vaultVersion        = 1
+	vaultKDF            = "scrypt"
+	vaultScryptN        = 1 << 15
+	vaultScryptR        = 8
+	vaultScryptP        = 1
+	vaultKeyLength      = 32
+	minPassphraseLength = 8
+
+	// serviceStartTimeout bounds how long materialized secrets stay on disk
+	// while waiting for CCR to come up
+	serviceStartTimeout = 10 * time.Second
+)
+
+// secretNamePattern restricts secret names to characters that are safe in a
+// reference and in log lines
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// vaultFile is the on-disk layout of the secret vault. Everything except the
+// key derivation parameters is encrypted.
+type vaultFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// vaultEntry is a single secret inside the encrypted payload
+type vaultEntry struct {
+	Value     string `json:"value"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// unlockedVault holds the derived key and decrypted secrets while the vault
+// is unlocked
+type unlockedVault struct {
+	key     []byte
+	salt    []byte
+	n, r, p int
+	entries map[string]vaultEntry
+}
+
+// VaultStatus describes the state of the secret vault
+type VaultStatus struct {
+	Path        string `json:"path"`
+	Exists      bool   `json:"exists"`
+	Unlocked    bool   `json:"unlocked"`
+	SecretCount int    `json:"secretCount"`
+}
+
+// SecretInfo describes a stored secret without its value
+type SecretInfo struct {
+	Name      string   `json:"name"`
+	Reference string   `json:"reference"`
+	CreatedAt string   `json:"createdAt"`
+	UpdatedAt string   `json:"updatedAt"`
+	UsedBy    []string `json:"usedBy"`
+}
+
+// GetVaultPath returns the path to the encrypted secret vault
+func (a *App) GetVaultPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "secrets.vault")
+}
+
+// vaultReference returns the config value referring to the named secret
+func vaultReference(name string) string {
+	return vaultRefPrefix + name
+}
+
+// vaultRefName returns the secret name referenced by value, if any
+func vaultRefName(value string) (string, bool) {
+	if !strings.HasPrefix(value, vaultRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, vaultRefPrefix), true
+}
+
+// secretFields lists the config values that may hold a vault reference
+func secretFields(config Config) []configValue {
+	fields := []configValue{{Path: "APIKEY", Value: config.APIKEY}}
+	for i, provider := range config.Providers {
+		fields = append(fields, configValue{Path: fmt.Sprintf("Providers[%d].api_key", i), Value: provider.APIKey})
+	}
+	return fields
+}
+
+// deriveVaultKey derives the vault encryption key from a passphrase
+func deriveVaultKey(passphrase string, salt []byte, n, r, p int) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, n, r, p, vaultKeyLength)
+}
+
+// newVaultCipher creates the AES-GCM cipher for key
+func newVaultCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// openVault decrypts vault file content with passphrase
+func openVault(data []byte, passphrase string) (*unlockedVault, error) {
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %v", err)
+	}
+	if file.Version != vaultVersion || file.KDF != vaultKDF {
+		return nil, fmt.Errorf("unsupported vault format (version %d, kdf %q)", file.Version, file.KDF)
+	}
+
+	key, err := deriveVaultKey(passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %v", err)
+	}
+	gcm, err := newVaultCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("vault is corrupted: invalid nonce")
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect passphrase or corrupted vault")
+	}
+
+	entries := make(map[string]vaultEntry)
+	if err := json.Unmarshal(plaintext, &entries); err != nil {
+		return nil, fmt.Errorf("vault is corrupted: %v", err)
+	}
+
+	return &unlockedVault{key: key, salt: file.Salt, n: file.N, r: file.R, p: file.P, entries: entries}, nil
+}
+
+// seal encrypts the vault with a fresh nonce
+func (v *unlockedVault) seal() ([]byte, error) {
+	plaintext, err := json.Marshal(v.entries)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newVaultCipher(v.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(vaultFile{
+		Version:    vaultVersion,
+		KDF:        vaultKDF,
+		N:          v.n,
+		R:          v.r,
+		P:          v.p,
+		Salt:       v.salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+}
+
+// newUnlockedVault derives a key for passphrase with a fresh salt
+func newUnlockedVault(passphrase string, entries map[string]vaultEntry) (*unlockedVault, error) {
+	if len(passphrase) < minPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveVaultKey(passphrase, salt, vaultScryptN, vaultScryptR, vaultScryptP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %v", err)
+	}
+	return &unlockedVault{key: key, salt: salt, n: vaultScryptN, r: vaultScryptR, p: vaultScryptP, entries: entries}, nil
+}
+
+// saveVault encrypts and writes the vault. The caller must hold vaultMu.
+func (a *App) saveVault(v *unlockedVault) error {
+	vaultPath := a.GetVaultPath()
+	if vaultPath == "" {
+		return fmt.Errorf("could not determine vault path")
+	}
+	data, err := v.seal()
+	if err != nil {
+		return fmt.Errorf("failed to encrypt vault: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(vaultPath), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(vaultPath, data, 0600); err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to write vault: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// unlockedVaultLocked returns the unlocked vault or an error. The caller must
+// hold vaultMu.
+func (a *App) unlockedVaultLocked() (*unlockedVault, error) {
+	if a.vault == nil {
+		return nil, fmt.Errorf("vault is locked")
+	}
+	return a.vault, nil
+}
+
+// GetVaultStatus reports whether the vault exists and is unlocked
+func (a *App) GetVaultStatus() VaultStatus {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	status := VaultStatus{Path: a.GetVaultPath()}
+	if _, err := os.Stat(status.Path); err == nil {
+		status.Exists = true
+	}
+	if a.vault != nil {
+		status.Unlocked = true
+		status.SecretCount = len(a.vault.entries)
+	}
+	return status
+}
+
+// CreateVault creates an empty vault protected by passphrase and unlocks it
+func (a *App) CreateVault(passphrase string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if _, err := os.Stat(a.GetVaultPath()); err == nil {
+		return fmt.Errorf("vault already exists")
+	}
+
+	v, err := newUnlockedVault(passphrase, make(map[string]vaultEntry))
+	if err != nil {
+		return err
+	}
+	if err := a.saveVault(v); err != nil {
+		return err
+	}
+	a.vault = v
+
+	if a.logger != nil {
+		a.logger.Printf("Created secret vault at %s", a.GetVaultPath())
+	}
+	return nil
+}
+
+// UnlockVault decrypts the vault and keeps its key in memory until LockVault
+func (a *App) UnlockVault(passphrase string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	data, err := os.ReadFile(a.GetVaultPath())
+	if os.IsNotExist(err) {
+		return fmt.Errorf("vault does not exist")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %v", err)
+	}
+
+	v, err := openVault(data, passphrase)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: Failed to unlock vault: %v", err)
+		}
+		return err
+	}
+	a.vault = v
+
+	if a.logger != nil {
+		a.logger.Printf("Unlocked secret vault")
+	}
+	return nil
+}
+
+// LockVault forgets the vault key and decrypted secrets
+func (a *App) LockVault() {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	if a.vault == nil {
+		return
+	}
+	for i := range a.vault.key {
+		a.vault.key[i] = 0
+	}
+	a.vault = nil
+
+	if a.logger != nil {
+		a.logger.Printf("Locked secret vault")
+	}
+}
+
+// ChangeVaultPassphrase re-encrypts the vault under a new passphrase
+func (a *App) ChangeVaultPassphrase(oldPassphrase, newPassphrase string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	data, err := os.ReadFile(a.GetVaultPath())
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %v", err)
+	}
+	current, err := openVault(data, oldPassphrase)
+	if err != nil {
+		return err
+	}
+
+	v, err := newUnlockedVault(newPassphrase, current.entries)
+	if err != nil {
+		return err
+	}
+	if err := a.saveVault(v); err != nil {
+		return err
+	}
+	a.vault = v
+
+	if a.logger != nil {
+		a.logger.Printf("Changed secret vault passphrase")
+	}
+	return nil
+}
+
+// ListSecrets lists the stored secrets and the config fields using them
+func (a *App) ListSecrets() ([]SecretInfo, error) {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	secrets := []SecretInfo{}
+	v, err := a.unlockedVaultLocked()
+	if err != nil {
+		return secrets, err
+	}
+
+	usedBy := a.secretUsage()
+	for name, entry := range v.entries {
+		secrets = append(secrets, SecretInfo{
+			Name:      name,
+			Reference: vaultReference(name),
+			CreatedAt: entry.CreatedAt,
+			UpdatedAt: entry.UpdatedAt,
+			UsedBy:    append([]string{}, usedBy[name]...),
+		})
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// secretUsage maps secret names to the config fields referencing them in the
+// config file on disk
+func (a *App) secretUsage() map[string][]string {
+	usage := make(map[string][]string)
+	config, _, err := a.readConfig()
+	if err != nil {
+		return usage
+	}
+	for _, field := range secretFields(config) {
+		if name, ok := vaultRefName(field.Value); ok {
+			usage[name] = append(usage[name], field.Path)
+		}
+	}
+	return usage
+}
+
+// AddSecret stores a new secret and returns the reference to put in the config
+func (a *App) AddSecret(name, value string) (string, error) {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	v, err := a.unlockedVaultLocked()
+	if err != nil {
+		return "", err
+	}
+	if !secretNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	if value == "" {
+		return "", fmt.Errorf("secret value must not be empty")
+	}
+	if _, exists := v.entries[name]; exists {
+		return "", fmt.Errorf("secret %q already exists", name)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	v.entries[name] = vaultEntry{Value: value, CreatedAt: now, UpdatedAt: now}
+	if err := a.saveVault(v); err != nil {
+		delete(v.entries, name)
+		return "", err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Added secret %s", name)
+	}
+	return vaultReference(name), nil
+}
+
+// RotateSecret replaces the value of an existing secret. References in the
+// config stay the same.
+func (a *App) RotateSecret(name, value string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	v, err := a.unlockedVaultLocked()
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("secret value must not be empty")
+	}
+	previous, exists := v.entries[name]
+	if !exists {
+		return fmt.Errorf("secret %q does not exist", name)
+	}
+
+	entry := previous
+	entry.Value = value
+	entry.UpdatedAt = time.Now().Format(time.RFC3339)
+	v.entries[name] = entry
+	if err := a.saveVault(v); err != nil {
+		v.entries[name] = previous
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Rotated secret %s", name)
+	}
+	return nil
+}
+
+// RevealSecret returns the value of a secret. The passphrase is checked again
+// for every reveal, even while the vault is unlocked, and each reveal is logged.
+func (a *App) RevealSecret(name, passphrase string) (string, error) {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	data, err := os.ReadFile(a.GetVaultPath())
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault: %v", err)
+	}
+	v, err := openVault(data, passphrase)
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("WARNING: Refused to reveal secret %s: %v", name, err)
+		}
+		return "", err
+	}
+	entry, exists := v.entries[name]
+	if !exists {
+		return "", fmt.Errorf("secret %q does not exist", name)
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Revealed secret %s", name)
+	}
+	return entry.Value, nil
+}
+
+// DeleteSecret removes a secret. Secrets still referenced by the config file
+// are not deleted.
+func (a *App) DeleteSecret(name string) error {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	v, err := a.unlockedVaultLocked()
+	if err != nil {
+		return err
+	}
+	entry, exists := v.entries[name]
+	if !exists {
+		return fmt.Errorf("secret %q does not exist", name)
+	}
+	if paths := a.secretUsage()[name]; len(paths) > 0 {
+		return fmt.Errorf("secret %q is still used by %s", name, strings.Join(paths, ", "))
+	}
+
+	delete(v.entries, name)
+	if err := a.saveVault(v); err != nil {
+		v.entries[name] = entry
+		return err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Deleted secret %s", name)
+	}
+	return nil
+}
+
+// checkSecretReferences reports malformed vault references and, while the
+// vault is unlocked, references to secrets that do not exist
+func (a *App) checkSecretReferences(config Config) []ValidationIssue {
+	var issues []ValidationIssue
+
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	for _, field := range secretFields(config) {
+		name, ok := vaultRefName(field.Value)
+		if !ok {
+			continue
+		}
+		switch {
+		case !secretNamePattern.MatchString(name):
+			issues = append(issues, ValidationIssue{Path: field.Path, Severity: SeverityError, Message: fmt.Sprintf("invalid vault reference %q", field.Value)})
+		case a.vault == nil:
+			issues = append(issues, ValidationIssue{Path: field.Path, Severity: SeverityWarning, Message: fmt.Sprintf("vault is locked, secret %q cannot be checked", name)})
+		default:
+			if _, exists := a.vault.entries[name]; !exists {
+				issues = append(issues, ValidationIssue{Path: field.Path, Severity: SeverityError, Message: fmt.Sprintf("secret %q does not exist in the vault", name)})
+			}
+		}
+	}
+	return issues
+}
+
+// resolveVaultRefs returns a copy of config with vault references replaced by
+// the secret values
+func (a *App) resolveVaultRefs(config Config) (Config, error) {
+	a.vaultMu.Lock()
+	defer a.vaultMu.Unlock()
+
+	lookup := func(path, value string) (string, error) {
+		name, ok := vaultRefName(value)
+		if !ok {
+			return value, nil
+		}
+		if a.vault == nil {
+			return "", fmt.Errorf("%s refers to a vault secret, unlock the vault first", path)
+		}
+		entry, exists := a.vault.entries[name]
+		if !exists {
+			return "", fmt.Errorf("%s refers to missing secret %q", path, name)
+		}
+		return entry.Value, nil
+	}
+
+	var err error
+	if config.APIKEY, err = lookup("APIKEY", config.APIKEY); err != nil {
+		return config, err
+	}
+	providers := make([]Provider, len(config.Providers))
+	copy(providers, config.Providers)
+	for i := range providers {
+		path := fmt.Sprintf("Providers[%d].api_key", i)
+		if providers[i].APIKey, err = lookup(path, providers[i].APIKey); err != nil {
+			return config, err
+		}
+	}
+	config.Providers = providers
+
+	return config, nil
+}
+
+// hasVaultRefs reports whether any config value refers to the vault
+func hasVaultRefs(config Config) bool {
+	for _, field := range secretFields(config) {
+		if _, ok := vaultRefName(field.Value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// materializedConfigPath returns where the reference version of config.json
+// is kept while the materialized one is on disk
+func (a *App) materializedConfigPath() string {
+	configPath := a.GetConfigPath()
+	return filepath.Join(filepath.Dir(configPath), "."+filepath.Base(configPath)+".refs")
+}
+
+// materializeConfig writes config.json with vault references resolved so CCR
+// can read the real secrets while it starts. The returned function waits for
+// a CCR process other than the one running now to listen on the port, then
+// puts the reference version back. config.json is locked against saves in
+// between.
+func (a *App) materializeConfig() (func(), error) {
+	a.configMu.Lock()
+
+	config, data, err := a.readConfig()
+	if err != nil || data == nil || !hasVaultRefs(config) {
+		a.configMu.Unlock()
+		return func() {}, nil
+	}
+
+	resolved, err := a.resolveVaultRefs(config)
+	if err != nil {
+		a.configMu.Unlock()
+		return nil, err
+	}
+	materialized, err := json.MarshalIndent(resolved, "", "  ")
+	if err != nil {
+		a.configMu.Unlock()
+		return nil, err
+	}
+
+	// 记录启动前的实例，重启时旧进程仍在监听端口
+	port := config.PORT
+	previous := a.ccrInstanceOnPort(port)
+
+	// 先保存引用版本，程序意外退出时启动时据此恢复
+	pendingPath := a.materializedConfigPath()
+	if err := writeFileAtomic(pendingPath, data, 0600); err != nil {
+		a.configMu.Unlock()
+		return nil, err
+	}
+	if err := writeFileAtomic(a.GetConfigPath(), materialized, 0600); err != nil {
+		os.Remove(pendingPath)
+		a.configMu.Unlock()
+		return nil, err
+	}
+
+	if a.logger != nil {
+		a.logger.Printf("Materialized vault secrets for CCR")
+	}
+
+	return func() {
+		defer a.configMu.Unlock()
+
+		// 等待新启动的 CCR 监听端口，确保它已读取配置
+		deadline := time.Now().Add(serviceStartTimeout)
+		for {
+			if pid, running, _ := a.findProcessByPort(port); running && a.isNewInstance(previous, pid) {
+				break
+			}
+			if time.Now().After(deadline) {
+				if a.logger != nil {
+					a.logger.Printf("WARNING: CCR did not start listening on port %d within %v, restoring config with vault references anyway", port, serviceStartTimeout)
+				}
+				break
+			}
+			time.Sleep(250 * time.Millisecond)
+		}
+
+		a.restoreReferenceConfig()
+	}, nil
+}
+
+// restoreReferenceConfig puts back the reference version of config.json left
+// by materializeConfig. The caller must hold configMu.
+func (a *App) restoreReferenceConfig() {
+	pendingPath := a.materializedConfigPath()
+	data, err := os.ReadFile(pendingPath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = writeFileAtomic(a.GetConfigPath(), data, 0644)
+	}
+	if err != nil {
+		if a.logger != nil {
+			a.logger.Printf("ERROR: Failed to restore config with vault references: %v", err)
+		}
+		return
+	}
+	os.Remove(pendingPath)
+
+	if a.logger != nil {
+		a.logger.Printf("Restored config with vault references")
+	}
+}
+
+// recoverMaterializedConfig restores the reference version of config.json if
+// the manager exited while secrets were materialized
+func (a *App) recoverMaterializedConfig() {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+	a.restoreReferenceConfig()
+}
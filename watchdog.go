@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	defaultWatchdogMaxCrashes    = 5
+	defaultWatchdogWindowMinutes = 10
+
+	// EventWatchdogState is emitted with a WatchdogStatus whenever the
+	// watchdog changes state or records a crash
+	EventWatchdogState = "watchdog:state"
+
+	// watchdogPollInterval is how often a CCR started with `ccr start` is
+	// checked, supervised processes report their exit directly
+	watchdogPollInterval = 5 * time.Second
+	// watchdogStartTimeout is how long a started CCR may take to listen
+	// before the start counts as a crash
+	watchdogStartTimeout = 30 * time.Second
+	// watchdogBaseBackoff is the delay before the first restart, doubled for
+	// every further crash in the window up to watchdogMaxBackoff
+	watchdogBaseBackoff = 2 * time.Second
+	watchdogMaxBackoff  = 2 * time.Minute
+
+	// crashHistorySize is how many crash records are kept
+	crashHistorySize = 50
+	// crashOutputLines is how many lines of output are kept per crash
+	crashOutputLines = 20
+)
+
+// Watchdog states
+const (
+	// WatchdogDisabled means the watchdog is turned off in the settings
+	WatchdogDisabled = "disabled"
+	// WatchdogIdle means CCR was not started by the manager or was stopped
+	WatchdogIdle = "idle"
+	// WatchdogWatching means CCR is expected to run and is monitored
+	WatchdogWatching = "watching"
+	// WatchdogBackoff means CCR crashed and a restart is scheduled
+	WatchdogBackoff = "backoff"
+	// WatchdogGaveUp means CCR crashed too often and is no longer restarted
+	WatchdogGaveUp = "gave-up"
+)
+
+// CrashRecord describes one unexpected exit of CCR
+type CrashRecord struct {
+	Time string `json:"time"`
+	Mode string `json:"mode"`
+	PID  int    `json:"pid,omitempty"`
+	// ExitCode is -1 when it is unknown, such as for a CCR started with
+	// `ccr start`
+	ExitCode int    `json:"exitCode"`
+	Reason   string `json:"reason"`
+	// Output holds the last lines CCR wrote before it exited
+	Output []string `json:"output"`
+	// RestartDelayMs is the backoff before the restart, 0 when CCR was not
+	// restarted
+	RestartDelayMs int64 `json:"restartDelayMs,omitempty"`
+	GaveUp         bool  `json:"gaveUp,omitempty"`
+}
+
+// WatchdogStatus reports the watchdog and the crash history, newest first
+type WatchdogStatus struct {
+	Enabled       bool   `json:"enabled"`
+	State         string `json:"state"`
+	NextRestartAt string `json:"nextRestartAt,omitempty"`
+	// RecentCrashes counts the crashes in the current window
+	RecentCrashes int           `json:"recentCrashes"`
+	MaxCrashes    int           `json:"maxCrashes"`
+	WindowMinutes int           `json:"windowMinutes"`
+	Crashes       []CrashRecord `json:"crashes"`
+}
+
+// watchdog restarts CCR when it exits without being asked to
+type watchdog struct {
+	mu sync.Mutex
+	// wanted is set while the manager expects CCR to run
+	wanted   bool
+	wantedAt time.Time
+	state    string
+	// pid is the CCR process seen on the port in ServiceModeCLI
+	pid int
+	// resetAt starts the crash window over after a manual start
+	resetAt     time.Time
+	nextRestart time.Time
+	timer       *time.Timer
+	// generation invalidates restarts scheduled before a manual action
+	generation int
+
+	crashes []CrashRecord
+	loaded  bool
+	stop    chan struct{}
+}
+
+// GetCrashHistoryPath returns the file recording CCR crashes
+func (a *App) GetCrashHistoryPath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".claude-code-router", "crash-history.json")
+}
+
+// loadCrashesLocked reads the crash history once. The caller must hold
+// watchdog.mu.
+func (a *App) loadCrashesLocked() {
+	if a.watchdog.loaded {
+		return
+	}
+	a.watchdog.loaded = true
+
+	data, err := os.ReadFile(a.GetCrashHistoryPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &a.watchdog.crashes); err != nil && a.logger != nil {
+		a.logger.Printf("WARNING: Failed to parse crash history: %v", err)
+	}
+}
+
+// saveCrashesLocked writes the crash history. The caller must hold
+// watchdog.mu.
+func (a *App) saveCrashesLocked() {
+	historyPath := a.GetCrashHistoryPath()
+	if historyPath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(a.watchdog.crashes, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(historyPath), 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(historyPath, data, 0644)
+	}
+	if err != nil && a.logger != nil {
+		a.logger.Printf("ERROR: Failed to write crash history to %s: %v", historyPath, err)
+	}
+}
+
+// recentCrashesLocked counts the crashes within the window since the last
+// manual start. The caller must hold watchdog.mu.
+func (a *App) recentCrashesLocked(window time.Duration, now time.Time) int {
+	since := now.Add(-window)
+	if a.watchdog.resetAt.After(since) {
+		since = a.watchdog.resetAt
+	}
+	count := 0
+	for _, crash := range a.watchdog.crashes {
+		if at, err := time.Parse(time.RFC3339Nano, crash.Time); err == nil && at.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// watchdogStatusLocked snapshots the watchdog. The caller must hold
+// watchdog.mu.
+func (a *App) watchdogStatusLocked(settings ManagerSettings) WatchdogStatus {
+	a.loadCrashesLocked()
+
+	status := WatchdogStatus{
+		Enabled:       settings.Watchdog,
+		State:         a.watchdog.state,
+		MaxCrashes:    settings.WatchdogMaxCrashes,
+		WindowMinutes: settings.WatchdogWindowMinutes,
+		RecentCrashes: a.recentCrashesLocked(time.Duration(settings.WatchdogWindowMinutes)*time.Minute, time.Now()),
+		Crashes:       make([]CrashRecord, 0, len(a.watchdog.crashes)),
+	}
+	if status.State == "" {
+		status.State = WatchdogIdle
+	}
+	if !status.Enabled && status.State != WatchdogBackoff {
+		status.State = WatchdogDisabled
+	}
+	if status.State == WatchdogBackoff {
+		status.NextRestartAt = a.watchdog.nextRestart.Format(time.RFC3339)
+	}
+	for i := len(a.watchdog.crashes) - 1; i >= 0; i-- {
+		status.Crashes = append(status.Crashes, a.watchdog.crashes[i])
+	}
+	return status
+}
+
+// emitWatchdogState publishes the watchdog state to the frontend. The caller
+// must hold watchdog.mu.
+func (a *App) emitWatchdogState(settings ManagerSettings) {
+	if a.ctx != nil {
+		wailsruntime.EventsEmit(a.ctx, EventWatchdogState, a.watchdogStatusLocked(settings))
+	}
+}
+
+// cancelRestartLocked drops a scheduled restart. The caller must hold
+// watchdog.mu.
+func (a *App) cancelRestartLocked() {
+	if a.watchdog.timer != nil {
+		a.watchdog.timer.Stop()
+		a.watchdog.timer = nil
+	}
+	a.watchdog.generation++
+	a.watchdog.nextRestart = time.Time{}
+}
+
+// watchdogExpect tells the watchdog whether CCR should be running after the
+// user started or stopped it. A manual start begins a new crash window.
+func (a *App) watchdogExpect(running bool) {
+	settings, _ := a.GetManagerSettings()
+
+	a.watchdog.mu.Lock()
+	defer a.watchdog.mu.Unlock()
+
+	a.cancelRestartLocked()
+	a.watchdog.wanted = running
+	a.watchdog.pid = 0
+	if running {
+		now := time.Now()
+		a.watchdog.wantedAt = now
+		a.watchdog.resetAt = now
+		a.watchdog.state = WatchdogWatching
+	} else {
+		a.watchdog.state = WatchdogIdle
+	}
+	a.emitWatchdogState(settings)
+}
+
+// reportCrash records an unexpected exit of CCR and, when the watchdog is
+// enabled, schedules a restart or gives up after too many crashes
+func (a *App) reportCrash(crash CrashRecord) {
+	settings, _ := a.GetManagerSettings()
+	window := time.Duration(settings.WatchdogWindowMinutes) * time.Minute
+
+	a.watchdog.mu.Lock()
+	defer a.watchdog.mu.Unlock()
+
+	a.loadCrashesLocked()
+	now := time.Now()
+	crash.Time = now.Format(time.RFC3339Nano)
+	if crash.Output == nil {
+		crash.Output = []string{}
+	}
+
+	restart := settings.Watchdog && a.watchdog.wanted
+	var delay time.Duration
+	if restart {
+		// 包含本次崩溃
+		crashes := a.recentCrashesLocked(window, now) + 1
+		if crashes >= settings.WatchdogMaxCrashes {
+			crash.GaveUp = true
+			restart = false
+		} else {
+			delay = watchdogBaseBackoff << (crashes - 1)
+			if delay > watchdogMaxBackoff || delay <= 0 {
+				delay = watchdogMaxBackoff
+			}
+			crash.RestartDelayMs = delay.Milliseconds()
+		}
+	}
+
+	a.watchdog.crashes = append(a.watchdog.crashes, crash)
+	if len(a.watchdog.crashes) > crashHistorySize {
+		a.watchdog.crashes = a.watchdog.crashes[len(a.watchdog.crashes)-crashHistorySize:]
+	}
+	a.saveCrashesLocked()
+
+	a.cancelRestartLocked()
+	a.watchdog.pid = 0
+	switch {
+	case restart:
+		generation := a.watchdog.generation
+		a.watchdog.state = WatchdogBackoff
+		a.watchdog.nextRestart = now.Add(delay)
+		a.watchdog.timer = time.AfterFunc(delay, func() { a.watchdogRestart(generation) })
+	case crash.GaveUp:
+		a.watchdog.wanted = false
+		a.watchdog.state = WatchdogGaveUp
+	default:
+		a.watchdog.wanted = false
+		a.watchdog.state = WatchdogIdle
+	}
+	a.emitWatchdogState(settings)
+
+	if a.logger != nil {
+		switch {
+		case restart:
+			a.logger.Printf("WARNING: CCR crashed (%s), restarting in %v", crash.Reason, delay)
+		case crash.GaveUp:
+			a.logger.Printf("ERROR: CCR crashed (%s) %d times within %d minutes, giving up", crash.Reason, settings.WatchdogMaxCrashes, settings.WatchdogWindowMinutes)
+		default:
+			a.logger.Printf("WARNING: CCR crashed (%s)", crash.Reason)
+		}
+	}
+}
+
+// watchdogRestart starts CCR again unless the restart was cancelled
+func (a *App) watchdogRestart(generation int) {
+	settings, _ := a.GetManagerSettings()
+
+	a.watchdog.mu.Lock()
+	if a.watchdog.generation != generation || a.watchdog.state != WatchdogBackoff {
+		a.watchdog.mu.Unlock()
+		return
+	}
+	a.watchdog.timer = nil
+	a.watchdog.nextRestart = time.Time{}
+	if !settings.Watchdog {
+		// 退避期间关闭了看门狗
+		a.watchdog.wanted = false
+		a.watchdog.state = WatchdogIdle
+		a.emitWatchdogState(settings)
+		a.watchdog.mu.Unlock()
+		return
+	}
+	a.watchdog.state = WatchdogWatching
+	a.watchdog.wantedAt = time.Now()
+	a.emitWatchdogState(settings)
+	a.watchdog.mu.Unlock()
+
+	if a.logger != nil {
+		a.logger.Printf("Watchdog restarting CCR")
+	}
+	if err := a.startService(); err != nil {
+		crash := CrashRecord{
+			Mode:     settings.ServiceMode,
+			ExitCode: -1,
+			Reason:   fmt.Sprintf("restart failed: %v", err),
+		}
+		if settings.ServiceMode == ServiceModeCLI {
+			crash.Output = a.tailServiceLog()
+		}
+		a.reportCrash(crash)
+	}
+}
+
+// tailServiceLog returns the last lines of the CCR log file, which is all
+// there is to show for a CCR started with `ccr start`
+func (a *App) tailServiceLog() []string {
+	lines := []string{}
+	logs, err := a.ReadLogs()
+	if err != nil {
+		return lines
+	}
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > crashOutputLines {
+		lines = lines[len(lines)-crashOutputLines:]
+	}
+	return lines
+}
+
+// checkCLIService looks for a CCR started with `ccr start` that has gone
+// away. CCR removes its PID file on a clean shutdown, so a port that was
+// freed while the PID file still names the process means it crashed.
+func (a *App) checkCLIService(settings ManagerSettings) {
+	config, _, err := a.readConfig()
+	if err != nil {
+		return
+	}
+	pid, running, err := a.findProcessByPort(config.PORT)
+	if err != nil {
+		return
+	}
+	pidFilePID, _, hasPIDFile := a.readCCRPIDFile()
+
+	a.watchdog.mu.Lock()
+	if !a.watchdog.wanted || a.watchdog.state != WatchdogWatching {
+		a.watchdog.mu.Unlock()
+		return
+	}
+	if running {
+		a.watchdog.pid = pid
+		a.watchdog.mu.Unlock()
+		return
+	}
+
+	watched := a.watchdog.pid
+	var reason string
+	switch {
+	case watched != 0 && hasPIDFile && pidFilePID == watched:
+		reason = "CCR exited without removing its PID file"
+	case watched != 0:
+		// 在管理器之外正常停止，例如执行了 ccr stop
+		a.watchdog.wanted = false
+		a.watchdog.pid = 0
+		a.watchdog.state = WatchdogIdle
+		a.emitWatchdogState(settings)
+		a.watchdog.mu.Unlock()
+		if a.logger != nil {
+			a.logger.Printf("CCR (PID %d) was stopped outside the manager", watched)
+		}
+		return
+	case time.Since(a.watchdog.wantedAt) > watchdogStartTimeout:
+		reason = fmt.Sprintf("CCR did not listen on port %d within %v", config.PORT, watchdogStartTimeout)
+	default:
+		a.watchdog.mu.Unlock()
+		return
+	}
+	a.watchdog.mu.Unlock()
+
+	a.reportCrash(CrashRecord{
+		Mode:     ServiceModeCLI,
+		PID:      watched,
+		ExitCode: -1,
+		Reason:   reason,
+		Output:   a.tailServiceLog(),
+	})
+}
+
+// startWatchdog starts polling CCR. A CCR already running when the manager
+// starts is adopted so it is restarted if it crashes.
+func (a *App) startWatchdog() {
+	stop := make(chan struct{})
+	a.watchdog.mu.Lock()
+	a.watchdog.stop = stop
+	a.watchdog.mu.Unlock()
+
+	go func() {
+		if settings, _ := a.GetManagerSettings(); settings.Watchdog && settings.ServiceMode == ServiceModeCLI {
+			if config, _, err := a.readConfig(); err == nil {
+				pid, running, _ := a.findProcessByPort(config.PORT)
+				pidFilePID, _, hasPIDFile := a.readCCRPIDFile()
+				if running && hasPIDFile && pidFilePID == pid {
+					a.watchdogExpect(true)
+				}
+			}
+		}
+
+		ticker := time.NewTicker(watchdogPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			settings, _ := a.GetManagerSettings()
+			// 托管进程退出时由 handleExit 直接报告
+			if settings.Watchdog && settings.ServiceMode == ServiceModeCLI && !a.supervisorActive() {
+				a.checkCLIService(settings)
+			}
+		}
+	}()
+}
+
+// stopWatchdog stops polling and drops any scheduled restart
+func (a *App) stopWatchdog() {
+	a.watchdog.mu.Lock()
+	defer a.watchdog.mu.Unlock()
+
+	a.cancelRestartLocked()
+	a.watchdog.wanted = false
+	if a.watchdog.stop != nil {
+		close(a.watchdog.stop)
+		a.watchdog.stop = nil
+	}
+}
+
+// GetWatchdogStatus reports the watchdog state and the crash history
+func (a *App) GetWatchdogStatus() WatchdogStatus {
+	settings, _ := a.GetManagerSettings()
+
+	a.watchdog.mu.Lock()
+	defer a.watchdog.mu.Unlock()
+	return a.watchdogStatusLocked(settings)
+}
+
+// ClearCrashHistory forgets recorded crashes and lets a watchdog that gave
+// up restart CCR again after the next start
+func (a *App) ClearCrashHistory() error {
+	settings, _ := a.GetManagerSettings()
+
+	a.watchdog.mu.Lock()
+	defer a.watchdog.mu.Unlock()
+
+	a.watchdog.loaded = true
+	a.watchdog.crashes = nil
+	if a.watchdog.state == WatchdogGaveUp {
+		a.watchdog.state = WatchdogIdle
+	}
+	if err := os.Remove(a.GetCrashHistoryPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear crash history: %v", err)
+	}
+	a.emitWatchdogState(settings)
+	return nil
+}